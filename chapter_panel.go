@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+// The "chapters" panel: the chapters of an EPUB, the one we are reading is picked when it opens.
+// up/down picks one, return jumps to its first line, escape leaves.
+
+// NOTE: same as FILE_PANEL_ROWS, the header and the help line need the rest
+const CHAPTER_PANEL_ROWS = 10
+
+type ChapterPanel struct {
+	show     bool
+	font     *ttf.Font
+	font_w   int
+	text     MultiLine
+	chapters []Chapter
+	selected int
+}
+
+func NewChapterPanel(renderer *sdl.Renderer, font *ttf.Font) ChapterPanel {
+	cp := ChapterPanel{font: font}
+	cp.font_w, _, _ = font.SizeUTF8(" ")
+	cp.text.New(renderer, font)
+	return cp
+}
+
+// Open shows chapters with the one that line is in selected.
+func (cp *ChapterPanel) Open(renderer *sdl.Renderer, chapters []Chapter, line int) {
+	cp.chapters = chapters
+	cp.selected = ChapterAt(chapters, line)
+	if cp.selected < 0 {
+		cp.selected = 0
+	}
+	cp.show = true
+	cp.Redraw(renderer)
+}
+
+func (cp *ChapterPanel) Close() {
+	cp.show = false
+	cp.chapters = nil
+}
+
+// HandleKey returns the document line of the chapter that was picked with return.
+func (cp *ChapterPanel) HandleKey(renderer *sdl.Renderer, key sdl.Keycode) (int, bool) {
+	switch key {
+	case sdl.K_ESCAPE:
+		cp.Close()
+	case sdl.K_UP, sdl.K_DOWN:
+		if n := len(cp.chapters); n > 0 {
+			delta := 1
+			if key == sdl.K_UP {
+				delta = -1
+			}
+			cp.selected = ((cp.selected+delta)%n + n) % n
+			cp.Redraw(renderer)
+		}
+	case sdl.K_RETURN:
+		if cp.selected < len(cp.chapters) {
+			line := cp.chapters[cp.selected].Line
+			cp.Close()
+			return line, true
+		}
+	}
+	return -1, false
+}
+
+func (cp *ChapterPanel) panel_lines() []string {
+	lines := []string{"chapters", " "}
+	first := 0
+	if cp.selected >= CHAPTER_PANEL_ROWS {
+		first = cp.selected - CHAPTER_PANEL_ROWS + 1
+	}
+	for i := first; i < len(cp.chapters) && i < first+CHAPTER_PANEL_ROWS; i++ {
+		marker := "  "
+		if i == cp.selected {
+			marker = "> "
+		}
+		lines = append(lines, marker+cp.chapters[i].Title)
+	}
+	return append(lines, " ", fmt.Sprintf("%d/%d [return] go to it [esc] back", cp.selected+1, len(cp.chapters)))
+}
+
+func (cp *ChapterPanel) Redraw(renderer *sdl.Renderer) {
+	var lines []string
+	for _, line := range cp.panel_lines() {
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, " ") // NOTE: RenderUTF8Blended fails on ""
+			continue
+		}
+		// NOTE: a long title would push the rest of the list off the panel
+		wrapped := NewWrapLines(line, int(cp.text.bg_rect.W), cp.font_w)
+		lines = append(lines, wrapped[0])
+	}
+	cp.text.ClearAndWrite(renderer, cp.font, lines, cp.text.lineskip, 0)
+}
+
+func (cp *ChapterPanel) Draw(renderer *sdl.Renderer) {
+	w, h, _ := renderer.GetOutputSize()
+	pad := int32(10)
+	cp.text.bg_rect.X = (w - cp.text.bg_rect.W) / 2
+	cp.text.bg_rect.Y = (h - cp.text.bg_rect.H) / 2
+	bg := sdl.Rect{X: cp.text.bg_rect.X - pad, Y: cp.text.bg_rect.Y - pad, W: cp.text.bg_rect.W + pad*2, H: cp.text.bg_rect.H + pad*2}
+	draw_rect_with_border_filled(renderer, &bg, &COLOR_IRON)
+	renderer.Copy(cp.text.texture, nil, &cp.text.bg_rect)
+}
+
+func (cp *ChapterPanel) Destroy() {
+	cp.text.texture.Destroy()
+	cp.text.fmt.Free()
+	cp.font.Close()
+}
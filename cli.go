@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// RunCommand runs one of the windowless subcommands: tester [flags] <command> [args]
+func RunCommand(args []string, config AppConfig) error {
+	switch args[0] {
+	case "vocab", "stats":
+	default:
+		return fmt.Errorf("unknown command '%s', expected one of: vocab, stats", args[0])
+	}
+
+	db, err := DBOpen(config.db_path, config.db_timeout)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err = DBMigrate(db); err != nil {
+		return err
+	}
+
+	if args[0] == "stats" {
+		return RunStatsCommand(db, config, args[1:], os.Stdout)
+	}
+	return RunVocabCommand(db, args[1:], os.Stdout)
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"bytes"
-	_ "fmt"
+	"fmt"
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/veandco/go-sdl2/ttf"
+	"sort"
+	"strings"
 )
 
 // TODO: create one texture and just draw into it instead of create/delete calls
@@ -21,8 +23,27 @@ type CmdConsole struct {
 	font_h       int
 	ttf_texture  *sdl.Texture
 	input_buffer bytes.Buffer
+
+	message_texture *sdl.Texture
+	message_rect    sdl.Rect
+
+	commands map[string]ConsoleCommand
+
+	// the drop-down above bg_rect, completions[selected] is what Tab puts in
+	completer           *TrieNode
+	load_completer      func() *TrieNode
+	completions         []string
+	completion_textures []*sdl.Texture
+	completion_rects    []sdl.Rect
+	selected            int
 }
 
+// how many completions the drop-down shows
+const CONSOLE_COMPLETIONS = 5
+
+// A ConsoleCommand gets the words typed after its name, what it returns goes to ShowMessage.
+type ConsoleCommand func(args []string) (string, error)
+
 func NewCmdConsole(renderer *sdl.Renderer) CmdConsole {
 	cmd := CmdConsole{}
 	cmd.alpha_value = 100
@@ -57,14 +78,72 @@ func NewCmdConsole(renderer *sdl.Renderer) CmdConsole {
 		H: int32(cmd.font_h),
 	}
 	cmd.input_buffer.Grow(64)
+	cmd.commands = make(map[string]ConsoleCommand)
 	return cmd
 }
 
+func (cmd *CmdConsole) Register(name string, fn ConsoleCommand) {
+	cmd.commands[name] = fn
+}
+
+func (cmd *CmdConsole) CommandNames() []string {
+	names := make([]string, 0, len(cmd.commands))
+	for name := range cmd.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Execute runs what was typed into the console, "rules reload" calls the "rules" command with ["reload"].
+func (cmd *CmdConsole) Execute(renderer *sdl.Renderer, line string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return
+	}
+	fn, ok := cmd.commands[fields[0]]
+	if !ok {
+		cmd.ShowMessage(renderer, fmt.Sprintf("[ERROR] unknown command '%s', try: %s", fields[0], strings.Join(cmd.CommandNames(), ", ")))
+		return
+	}
+	msg, err := fn(fields[1:])
+	if err != nil {
+		cmd.ShowMessage(renderer, "[ERROR] "+fields[0]+": "+err.Error())
+		return
+	}
+	cmd.ShowMessage(renderer, msg)
+}
+
 func (cmd *CmdConsole) Resize(new_win_w int32, new_win_h int32) {
 	cmd.bg_rect.W = new_win_w
 	cmd.bg_rect.Y = new_win_h - cmd.cursor_rect.H
 	cmd.ttf_rect.Y = new_win_h - cmd.cursor_rect.H
 	cmd.cursor_rect.Y = new_win_h - cmd.cursor_rect.H
+	cmd.message_rect.Y = cmd.bg_rect.Y - (cmd.bg_rect.H * 2)
+}
+
+// ShowMessage is how we report errors that shouldn't crash the app,
+// msg goes on its own line above the debug line and stays there until the next ShowMessage.
+// An empty msg removes the current one.
+func (cmd *CmdConsole) ShowMessage(renderer *sdl.Renderer, msg string) {
+	if cmd.message_texture != nil {
+		cmd.message_texture.Destroy()
+		cmd.message_texture = nil
+	}
+	if msg == "" {
+		return
+	}
+	cmd.message_texture = MakeTTF_Texture(renderer, cmd.font, msg, &COLOR_RED)
+	_, _, tw, th, _ := cmd.message_texture.Query()
+	cmd.message_rect = sdl.Rect{X: 0, Y: cmd.bg_rect.Y - (cmd.bg_rect.H * 2), W: tw, H: th}
+	cmd.show = true
+}
+
+func (cmd *CmdConsole) DrawMessage(renderer *sdl.Renderer) {
+	if cmd.message_texture != nil {
+		draw_rect_with_border_filled(renderer, &cmd.message_rect, &COLOR_IRON)
+		renderer.Copy(cmd.message_texture, nil, &cmd.message_rect)
+	}
 }
 
 func (cmd *CmdConsole) MakeTexture(renderer *sdl.Renderer, text string, color *sdl.Color) {
@@ -84,6 +163,7 @@ func (cmd *CmdConsole) WriteChar(renderer *sdl.Renderer, t uint8) {
 		cmd.ttf_rect.W = int32(cmd.font_w * len(cmd.input_buffer.String()))
 		cmd.ttf_rect.H = int32(cmd.font_h)
 		cmd.cursor_rect.X += int32(curr_char_w)
+		cmd.UpdateCompletions(renderer)
 	}
 }
 
@@ -93,13 +173,14 @@ func (cmd *CmdConsole) WriteString(renderer *sdl.Renderer, str string) {
 			break
 		}
 		cmd.input_buffer.WriteString(string(str[i]))
-		curr_char_w := cmd.font_w * len(str)
+		curr_char_w := cmd.font_w
 		cmd.ttf_rect.W = int32(cmd.font_w * len(cmd.input_buffer.String()))
 		cmd.ttf_rect.H = int32(cmd.font_h)
 		cmd.cursor_rect.X += int32(curr_char_w)
 	}
 	cmd.ttf_texture.Destroy()
 	cmd.MakeTexture(renderer, cmd.input_buffer.String(), &sdl.Color{R: 0, G: 0, B: 0, A: 255})
+	cmd.UpdateCompletions(renderer)
 }
 
 func (cmd *CmdConsole) Reset(renderer *sdl.Renderer) {
@@ -124,6 +205,7 @@ func (cmd *CmdConsole) Reset(renderer *sdl.Renderer) {
 		} else {
 			cmd.cursor_rect.X = 0
 		}
+		cmd.UpdateCompletions(renderer)
 	}
 }
 
@@ -134,4 +216,106 @@ func (cmd *CmdConsole) MakeNULL() {
 		cmd.ttf_texture = nil
 	}
 	cmd.cursor_rect.X = 0
+	cmd.clear_completions()
+}
+
+func (cmd *CmdConsole) Destroy() {
+	cmd.MakeNULL()
+	if cmd.message_texture != nil {
+		cmd.message_texture.Destroy()
+		cmd.message_texture = nil
+	}
+	cmd.font.Close()
+}
+
+// SetCompleter is where the completions come from, the vocabulary and the command names.
+// NOTE: load is only called once the user starts typing, a big vocabulary shouldn't slow down the startup.
+func (cmd *CmdConsole) SetCompleter(load func() *TrieNode) {
+	cmd.completer = nil
+	cmd.load_completer = load
+}
+
+// completion_prefix is the word being typed, "" right after a space.
+func (cmd *CmdConsole) completion_prefix() string {
+	input := cmd.input_buffer.String()
+	return input[strings.LastIndex(input, " ")+1:]
+}
+
+// complete_word is up to n words of trie that start with prefix, prefix itself is already complete.
+func complete_word(trie *TrieNode, prefix string, n int) []string {
+	var words []string
+	for _, word := range trie.KeysWithPrefix(prefix, n+1) {
+		if word != prefix && len(words) < n {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+func (cmd *CmdConsole) clear_completions() {
+	for _, texture := range cmd.completion_textures {
+		texture.Destroy()
+	}
+	cmd.completions = nil
+	cmd.completion_textures = nil
+	cmd.completion_rects = nil
+	cmd.selected = 0
+}
+
+// UpdateCompletions looks up the word being typed, the first completion goes right above bg_rect
+// and the rest stack up from there, lined up with the word.
+func (cmd *CmdConsole) UpdateCompletions(renderer *sdl.Renderer) {
+	cmd.clear_completions()
+	prefix := cmd.completion_prefix()
+	if prefix == "" {
+		return
+	}
+	if cmd.completer == nil && cmd.load_completer != nil {
+		cmd.completer = cmd.load_completer()
+	}
+	if cmd.completer == nil {
+		return
+	}
+	cmd.completions = complete_word(cmd.completer, prefix, CONSOLE_COMPLETIONS)
+	x := int32(cmd.font_w * (cmd.input_buffer.Len() - len(prefix)))
+	for i, word := range cmd.completions {
+		texture := MakeTTF_Texture(renderer, cmd.font, word, &sdl.Color{R: 0, G: 0, B: 0, A: 255})
+		_, _, tw, th, _ := texture.Query()
+		cmd.completion_textures = append(cmd.completion_textures, texture)
+		cmd.completion_rects = append(cmd.completion_rects, sdl.Rect{X: x, Y: cmd.bg_rect.Y - th*int32(i+1), W: tw, H: th})
+	}
+}
+
+func (cmd *CmdConsole) HasCompletions() bool {
+	return len(cmd.completions) > 0
+}
+
+// SelectCompletion moves the highlight up (-1) or down (+1) the list, it wraps around.
+func (cmd *CmdConsole) SelectCompletion(delta int) {
+	if n := len(cmd.completions); n > 0 {
+		cmd.selected = ((cmd.selected+delta)%n + n) % n
+	}
+}
+
+// AcceptCompletion puts the selected completion in place of the word being typed.
+func (cmd *CmdConsole) AcceptCompletion(renderer *sdl.Renderer) bool {
+	if !cmd.HasCompletions() {
+		return false
+	}
+	input := cmd.input_buffer.String()
+	line := input[:len(input)-len(cmd.completion_prefix())] + cmd.completions[cmd.selected] + " "
+	cmd.MakeNULL()
+	cmd.WriteString(renderer, line)
+	return true
+}
+
+func (cmd *CmdConsole) DrawCompletions(renderer *sdl.Renderer) {
+	for i := range cmd.completion_textures {
+		color := &COLOR_IRON
+		if i == cmd.selected {
+			color = &COLOR_WHITE
+		}
+		draw_rect_with_border_filled(renderer, &cmd.completion_rects[i], color)
+		renderer.Copy(cmd.completion_textures[i], nil, &cmd.completion_rects[i])
+	}
 }
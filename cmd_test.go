@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompleteWord(t *testing.T) {
+	trie := NewTrie()
+	for _, word := range []string{"rules", "maison", "mais", "maisonnette", "maître", "main"} {
+		trie.Insert(word)
+	}
+	test := []struct {
+		prefix   string
+		n        int
+		expected []string
+	}{
+		{prefix: "mai", n: 5, expected: []string{"main", "mais", "maison", "maisonnette"}},
+		{prefix: "maî", n: 5, expected: []string{"maître"}},
+		{prefix: "mai", n: 2, expected: []string{"main", "mais"}},
+		{prefix: "mais", n: 5, expected: []string{"maison", "maisonnette"}},
+		{prefix: "maisonnette", n: 5, expected: nil},
+		{prefix: "ru", n: 5, expected: []string{"rules"}},
+		{prefix: "x", n: 5, expected: nil},
+	}
+	for _, tt := range test {
+		if words := complete_word(trie, tt.prefix, tt.n); !reflect.DeepEqual(words, tt.expected) {
+			t.Errorf("complete_word(%s, %d) failed: got (%q) expected (%q)", tt.prefix, tt.n, words, tt.expected)
+		}
+	}
+}
+
+func TestCompletionPrefix(t *testing.T) {
+	test := []struct {
+		input    string
+		expected string
+	}{
+		{input: "", expected: ""},
+		{input: "ru", expected: "ru"},
+		{input: "rules ", expected: ""},
+		{input: "rules rel", expected: "rel"},
+	}
+	for _, tt := range test {
+		var cmd CmdConsole
+		cmd.input_buffer.WriteString(tt.input)
+		if prefix := cmd.completion_prefix(); prefix != tt.expected {
+			t.Errorf("completion_prefix(%s) failed: got (%s) expected (%s)", tt.input, prefix, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"tester/tokenizer"
+)
+
+// Settings files are plain "key = value" lines, '#' starts a comment line.
+// Every text can have a sidecar next to it, e.g. ./text/French.txt.cfg:
+//
+//	lang = fr
+//
+// and the app itself reads CONFIG_NAME from the user's config dir (or from -config):
+//
+//	db = /home/me/shared/vocabulary.db
+//	db_timeout = 2s
+//	cedict = ~/dict/cedict_ts.u8
+//	lemmas.fr = ~/dict/lemmatization-fr.txt
+//	rules_dir = ~/.config/tester/rules
+//
+// rules_dir has a <lang>.cfg with the tokenizer.Rules of every language that needs them,
+// by default it's the "rules" dir next to the config file.
+const SETTINGS_EXT = ".cfg"
+const CONFIG_NAME = "tester.cfg"
+const DEFAULT_TEXT_DIR = "./text/"
+
+type AppConfig struct {
+	db_path    string
+	db_timeout time.Duration
+	cedict     string            // CC-CEDICT file for segmenting Chinese, "" cuts it into single characters
+	lemmas     map[string]string // lemma file by language, see LoadLemmaFile
+	rules_dir  string
+}
+
+func DefaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return CONFIG_NAME
+	}
+	return filepath.Join(dir, "tester", CONFIG_NAME)
+}
+
+// LoadAppConfig returns the defaults (./my.db) if the file doesn't exist,
+// a broken setting is reported but doesn't stop us from using the rest of them.
+func LoadAppConfig(path string) (AppConfig, error) {
+	config := AppConfig{db_path: DEFAULT_DB_NAME, db_timeout: DEFAULT_DB_TIMEOUT, lemmas: make(map[string]string)}
+	if wdir, err := os.Getwd(); err == nil {
+		config.db_path = filepath.Join(wdir, DEFAULT_DB_NAME)
+	}
+	config.rules_dir = filepath.Join(filepath.Dir(path), "rules")
+
+	settings, err := ReadSettings(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	if db := settings["db"]; db != "" {
+		config.db_path = expand_home(os.ExpandEnv(db))
+	}
+	if rules_dir := settings["rules_dir"]; rules_dir != "" {
+		config.rules_dir = expand_home(os.ExpandEnv(rules_dir))
+	}
+	if cedict := settings["cedict"]; cedict != "" {
+		config.cedict = expand_home(os.ExpandEnv(cedict))
+	}
+	for key, value := range settings {
+		if lang := strings.TrimPrefix(key, "lemmas."); lang != key && lang != "" && value != "" {
+			config.lemmas[lang] = expand_home(os.ExpandEnv(value))
+		}
+	}
+	if timeout := settings["db_timeout"]; timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return config, fmt.Errorf("%s: bad db_timeout '%s': %v", path, timeout, err)
+		}
+		config.db_timeout = d
+	}
+	return config, nil
+}
+
+// UseLanguage switches the lemmas and the tokenizer rules to lang's.
+// A broken file is reported, and we go on without it.
+func UseLanguage(config AppConfig, lang string) error {
+	lemma_err := use_lemmas(config, lang)
+	rules, err := LoadRules(config, lang)
+	TEXT_RULES = rules
+	if lemma_err != nil {
+		return lemma_err
+	}
+	return err
+}
+
+// LoadRules reads rules_dir/<lang>.cfg every time, so editing the file and calling it again is a reload.
+// Without the file it's tokenizer.DEFAULT_RULES.
+func LoadRules(config AppConfig, lang string) (*tokenizer.Rules, error) {
+	path := filepath.Join(config.rules_dir, lang+SETTINGS_EXT)
+	settings, err := ReadSettings(path)
+	if os.IsNotExist(err) {
+		return tokenizer.DEFAULT_RULES, nil
+	}
+	if err != nil {
+		return tokenizer.DEFAULT_RULES, err
+	}
+	rules, err := tokenizer.ParseRules(settings)
+	if err != nil {
+		return tokenizer.DEFAULT_RULES, fmt.Errorf("%s: %v", path, err)
+	}
+	return rules, nil
+}
+
+func expand_home(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
+
+func ReadSettings(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	result := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected 'key = value', got '%s'", path, lineno, line)
+		}
+		result[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TextLanguage picks the vocabulary language for path/filename.
+// An explicit -lang flag wins over the sidecar setting, and DEFAULT_LANGUAGE is the last resort.
+func TextLanguage(path string, filename string, flag_lang string) string {
+	if flag_lang != "" {
+		return flag_lang
+	}
+	settings, err := ReadSettings(path + filename + SETTINGS_EXT)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Println("[WARNING]", err)
+		}
+		return DEFAULT_LANGUAGE
+	}
+	if lang := settings["lang"]; lang != "" {
+		return lang
+	}
+	return DEFAULT_LANGUAGE
+}
+
+// TextEncoding is the "encoding" setting of path/filename, "" if it isn't set, see DecodeText.
+func TextEncoding(path string, filename string) string {
+	settings, err := ReadSettings(path + filename + SETTINGS_EXT)
+	if err != nil {
+		return "" // NOTE: TextLanguage already warns about a broken settings file
+	}
+	return settings["encoding"]
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tester/tokenizer"
+)
+
+func TestTextLanguage(t *testing.T) {
+	dir := t.TempDir() + "/"
+	os.WriteFile(filepath.Join(dir, "French.txt"+SETTINGS_EXT), []byte("# sidecar\n lang = fr \n"), FILE_MODE_RW)
+	os.WriteFile(filepath.Join(dir, "Broken.txt"+SETTINGS_EXT), []byte("lang fr\n"), FILE_MODE_RW)
+
+	test := []struct {
+		filename string
+		flag     string
+		expected string
+	}{
+		{filename: "French.txt", flag: "", expected: "fr"},
+		{filename: "French.txt", flag: "en", expected: "en"},
+		{filename: "Hanyu.txt", flag: "", expected: DEFAULT_LANGUAGE},
+		{filename: "Broken.txt", flag: "", expected: DEFAULT_LANGUAGE},
+	}
+	for _, tt := range test {
+		if result := TextLanguage(dir, tt.filename, tt.flag); result != tt.expected {
+			t.Errorf("TestTextLanguage failed: (%s, %s) got (%s) expected (%s)", tt.filename, tt.flag, result, tt.expected)
+		}
+	}
+}
+
+func TestLoadAppConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	config, err := LoadAppConfig(filepath.Join(dir, "missing.cfg"))
+	if err != nil || filepath.Base(config.db_path) != DEFAULT_DB_NAME || config.db_timeout != DEFAULT_DB_TIMEOUT {
+		t.Errorf("LoadAppConfig defaults failed: got (%+v, %v)", config, err)
+	}
+
+	path := filepath.Join(dir, CONFIG_NAME)
+	os.WriteFile(path, []byte("db = /srv/vocab/shared.db\ndb_timeout = 250ms\nlemmas.fr = /srv/dict/fr.txt\n"), FILE_MODE_RW)
+	config, err = LoadAppConfig(path)
+	if err != nil || config.db_path != "/srv/vocab/shared.db" || config.db_timeout.Milliseconds() != 250 {
+		t.Errorf("LoadAppConfig failed: got (%+v, %v)", config, err)
+	}
+	if config.lemmas["fr"] != "/srv/dict/fr.txt" || len(config.lemmas) != 1 {
+		t.Errorf("LoadAppConfig failed: got lemmas (%v)", config.lemmas)
+	}
+
+	t.Setenv("HOME", dir)
+	os.WriteFile(path, []byte("db = ~/vocab.db\ncedict = ~/dict/cedict.txt\n"), FILE_MODE_RW)
+	config, err = LoadAppConfig(path)
+	if err != nil || config.db_path != filepath.Join(dir, "vocab.db") || config.cedict != filepath.Join(dir, "dict", "cedict.txt") {
+		t.Errorf("LoadAppConfig failed to expand '~': got (%q, %q, %v)", config.db_path, config.cedict, err)
+	}
+
+	os.WriteFile(path, []byte("db_timeout = soon\n"), FILE_MODE_RW)
+	if _, err = LoadAppConfig(path); err == nil {
+		t.Errorf("LoadAppConfig should fail on a bad db_timeout")
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	config := AppConfig{rules_dir: t.TempDir()}
+
+	rules, err := LoadRules(config, "fr")
+	if err != nil || rules != tokenizer.DEFAULT_RULES {
+		t.Errorf("LoadRules failed without a rules file: got (%+v, %v)", rules, err)
+	}
+
+	path := filepath.Join(config.rules_dir, "fr"+SETTINGS_EXT)
+	os.WriteFile(path, []byte("# fr\nelisions = l qu\nabbreviations = M. etc.\nignore = Paris\n"), FILE_MODE_RW)
+	rules, err = LoadRules(config, "fr")
+	if err != nil || len(rules.Elisions) != 2 || !rules.IsAbbreviation("M.") {
+		t.Errorf("LoadRules failed: got (%+v, %v)", rules, err)
+	}
+
+	defer func(rules *tokenizer.Rules) { TEXT_RULES = rules }(TEXT_RULES)
+	TEXT_RULES = rules
+	words := GetUniqueWords([]string{"l'homme de Paris"})
+	if _, ok := words["homme"]; !ok || len(words) != 2 {
+		t.Errorf("GetUniqueWords failed with rules: got (%v)", words)
+	}
+	sentences := SplitSentences("M. Dupont est là. Il dort, etc. Fin.")
+	if len(sentences) != 2 || sentences[0].text != "M. Dupont est là." {
+		t.Errorf("SplitSentences failed with abbreviations: got (%+v)", sentences)
+	}
+
+	os.WriteFile(path, []byte("elision = l\n"), FILE_MODE_RW)
+	if rules, err = LoadRules(config, "fr"); err == nil || rules != tokenizer.DEFAULT_RULES {
+		t.Errorf("LoadRules should fail on an unknown rule: got (%+v, %v)", rules, err)
+	}
+}
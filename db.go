@@ -1,71 +1,420 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	bolt "go.etcd.io/bbolt"
 	"os"
+	"path/filepath"
+	"time"
 )
 
 var FILE_MODE_RW os.FileMode = 0600
 
-func DBOpen() *bolt.DB {
-	wdir, err := os.Getwd()
+const (
+	DEFAULT_DB_NAME    = "my.db"
+	DEFAULT_DB_TIMEOUT = time.Second
+)
+
+var (
+	BUCKET_TEST_WORDS = []byte("TestWords") // legacy, single language
+	BUCKET_VOCABULARY = []byte("Vocabulary")
+	BUCKET_META       = []byte("Meta")
+	KEY_SCHEMA        = []byte("schema_version")
+)
+
+// NOTE: errors are wrapped with %w, so errors.Is finds WordNotFoundError and the like through them
+
+// used when neither the -lang flag nor the text's settings tell us the language
+const DEFAULT_LANGUAGE = "und" // BCP 47 "undetermined"
+
+// NOTE: every word value is [version byte][json payload].
+// Version 0 is the legacy empty value "" that we used to write for every word.
+const WORD_RECORD_VERSION uint8 = 1
+
+// SCHEMA_VERSION is stored under KEY_SCHEMA in BUCKET_META, see DBMigrate.
+const SCHEMA_VERSION uint8 = 2
+
+type WordStatus uint8
+
+const (
+	WORD_STATUS_NEW WordStatus = iota
+	WORD_STATUS_LEARNING
+	WORD_STATUS_KNOWN
+	WORD_STATUS_IGNORED
+)
+
+func (s WordStatus) String() string {
+	switch s {
+	case WORD_STATUS_NEW:
+		return "new"
+	case WORD_STATUS_LEARNING:
+		return "learning"
+	case WORD_STATUS_KNOWN:
+		return "known"
+	case WORD_STATUS_IGNORED:
+		return "ignored"
+	}
+	return "unknown"
+}
+
+func ParseWordStatus(s string) (WordStatus, error) {
+	switch s {
+	case "new", "":
+		return WORD_STATUS_NEW, nil
+	case "learning":
+		return WORD_STATUS_LEARNING, nil
+	case "known":
+		return WORD_STATUS_KNOWN, nil
+	case "ignored":
+		return WORD_STATUS_IGNORED, nil
+	}
+	return WORD_STATUS_NEW, fmt.Errorf("unknown word status '%s'", s)
+}
+
+// NextWordStatus is what a click on a word does: new -> learning -> known -> new.
+// Ignored words go back to new, they can only be ignored on purpose.
+func NextWordStatus(s WordStatus) WordStatus {
+	switch s {
+	case WORD_STATUS_NEW:
+		return WORD_STATUS_LEARNING
+	case WORD_STATUS_LEARNING:
+		return WORD_STATUS_KNOWN
+	}
+	return WORD_STATUS_NEW
+}
+
+type WordRecord struct {
+	Status    WordStatus `json:"status"`
+	FirstSeen time.Time  `json:"first_seen"`
+	LastSeen  time.Time  `json:"last_seen"`
+	Lookups   uint32     `json:"lookups"`
+	Note      string     `json:"note,omitempty"`
+
+	Review  *ReviewData  `json:"review,omitempty"`  // nil until the word is marked as learning
+	Context *WordContext `json:"context,omitempty"` // where we saw the word first
+}
+
+func NewWordRecord(now time.Time) WordRecord {
+	return WordRecord{Status: WORD_STATUS_NEW, FirstSeen: now, LastSeen: now}
+}
+
+// MergeWordRecord fills in what dst doesn't know yet from src, it never overrides what dst knows.
+// The only exception is the status: WORD_STATUS_NEW is what every word starts with, so it isn't really known.
+func MergeWordRecord(dst *WordRecord, src WordRecord) {
+	if dst.Status == WORD_STATUS_NEW {
+		dst.Status = src.Status
+	}
+	if !src.FirstSeen.IsZero() && (dst.FirstSeen.IsZero() || src.FirstSeen.Before(dst.FirstSeen)) {
+		dst.FirstSeen = src.FirstSeen
+	}
+	if src.LastSeen.After(dst.LastSeen) {
+		dst.LastSeen = src.LastSeen
+	}
+	if src.Lookups > dst.Lookups {
+		dst.Lookups = src.Lookups
+	}
+	if dst.Note == "" {
+		dst.Note = src.Note
+	}
+	if dst.Context == nil {
+		dst.Context = src.Context
+	}
+	if dst.Review == nil {
+		dst.Review = src.Review
+	}
+}
+
+func EncodeWordRecord(rec *WordRecord) ([]byte, error) {
+	payload, err := json.Marshal(rec)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	return append([]byte{WORD_RECORD_VERSION}, payload...), nil
+}
+
+func DecodeWordRecord(data []byte) (WordRecord, error) {
+	var rec WordRecord
+	if len(data) == 0 {
+		return rec, LegacyRecordError
+	}
+	if data[0] != WORD_RECORD_VERSION {
+		return rec, fmt.Errorf("unsupported record version '%d'", data[0])
+	}
+	if err := json.Unmarshal(data[1:], &rec); err != nil {
+		return rec, fmt.Errorf("Failed to decode record: '%w'", err)
+	}
+	if rec.Status > WORD_STATUS_IGNORED { // NOTE: written by a newer version, or garbage
+		rec.Status = WORD_STATUS_NEW
+	}
+	return rec, nil
+}
+
+// DBOpen doesn't panic, the app should keep running (without a vocabulary) if the db can't be opened.
+// timeout is how long we wait for the file lock when another instance has the db open.
+func DBOpen(path string, timeout time.Duration) (*bolt.DB, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("Failed to create '%s': %w", dir, err)
+		}
+	}
+	db, err := bolt.Open(path, FILE_MODE_RW, &bolt.Options{Timeout: timeout})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("'%s' is locked by another instance (waited %v)", path, timeout)
 	}
-	db, err := bolt.Open(wdir+"/my.db", FILE_MODE_RW, nil)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("Failed to open '%s': %w", path, err)
 	}
-	return db
+	return db, nil
 }
 
-func DBInit(db *bolt.DB, mk map[string]struct{}) error {
+// DBMigrate brings an older db up to SCHEMA_VERSION, one step at a time, so it's safe to call on every startup.
+//
+//	1: the legacy empty values in BUCKET_TEST_WORDS become WordRecords
+//	2: vocabulary keys are case folded, "Maison" and "maison" become one word
+func DBMigrate(db *bolt.DB) error {
 	err := db.Update(func(tx *bolt.Tx) error {
-		bucket, err := tx.CreateBucketIfNotExists([]byte("TestWords"))
+		meta, err := tx.CreateBucketIfNotExists(BUCKET_META)
+		if err != nil {
+			return fmt.Errorf("Failed to create bucket: %w", err)
+		}
+		version := uint8(0)
+		if v := meta.Get(KEY_SCHEMA); len(v) == 1 {
+			version = v[0]
+		}
+		if version >= SCHEMA_VERSION {
+			return nil
+		}
+		if version < 1 {
+			if err = migrate_legacy_values(tx); err != nil {
+				return err
+			}
+		}
+		if version < 2 {
+			if err = migrate_fold_keys(tx); err != nil {
+				return err
+			}
+		}
+		if err = drop_trie_snapshots(tx); err != nil {
+			return err
+		}
+		return meta.Put(KEY_SCHEMA, []byte{SCHEMA_VERSION})
+	})
+	if err != nil {
+		return fmt.Errorf("bbolt db.Update in DBMigrate failed '%w'", err)
+	}
+	return nil
+}
+
+func migrate_legacy_values(tx *bolt.Tx) error {
+	bucket, err := tx.CreateBucketIfNotExists(BUCKET_TEST_WORDS)
+	if err != nil {
+		return fmt.Errorf("Failed to create bucket: %w", err)
+	}
+	var legacy [][]byte
+	err = bucket.ForEach(func(k, v []byte) error {
+		if len(v) == 0 {
+			legacy = append(legacy, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// NOTE: we don't know when the legacy words were seen, so "now" is the best guess
+	now := time.Now()
+	for _, k := range legacy {
+		rec := NewWordRecord(now)
+		data, err := EncodeWordRecord(&rec)
+		if err != nil {
+			return err
+		}
+		if err = bucket.Put(k, data); err != nil {
+			return fmt.Errorf("Failed to migrate '%s': '%w'", k, err)
+		}
+	}
+	return nil
+}
+
+// migrate_fold_keys merges every word into its FoldWord key, see MergeWordRecord.
+// NOTE: "Maison" merges into "maison", not the other way around, so "maison" wins where both know something.
+func migrate_fold_keys(tx *bolt.Tx) error {
+	vocab, err := tx.CreateBucketIfNotExists(BUCKET_VOCABULARY)
+	if err != nil {
+		return fmt.Errorf("Failed to create bucket: %w", err)
+	}
+	return vocab.ForEach(func(lang, v []byte) error {
+		bucket := vocab.Bucket(lang)
+		if v != nil || bucket == nil {
+			return nil // not a language bucket
+		}
+		unfolded := make(map[string]WordRecord)
+		err := bucket.ForEach(func(k, v []byte) error {
+			if FoldWord(string(k)) == string(k) {
+				return nil
+			}
+			rec, err := DecodeWordRecord(v)
+			if err != nil {
+				return fmt.Errorf("Failed to decode '%s': '%w'", k, err)
+			}
+			unfolded[string(k)] = rec
+			return nil
+		})
 		if err != nil {
-			return fmt.Errorf("Failed to create bucket: %v", err)
+			return err
 		}
-		for k, _ := range mk {
-			if bucket.Get([]byte(k)) == nil { // don't override key/val if exists
-				err = bucket.Put([]byte(k), []byte(""))
-				if err != nil {
-					return fmt.Errorf("Failed to insert '%s': '%v'", k, err)
+		for word, src := range unfolded {
+			key := []byte(FoldWord(word))
+			rec := src
+			if old := bucket.Get(key); old != nil {
+				if rec, err = DecodeWordRecord(old); err != nil {
+					return fmt.Errorf("Failed to decode '%s': '%w'", key, err)
 				}
+				MergeWordRecord(&rec, src)
+			}
+			data, err := EncodeWordRecord(&rec)
+			if err != nil {
+				return err
+			}
+			if err = bucket.Put(key, data); err != nil {
+				return fmt.Errorf("Failed to migrate '%s': '%w'", word, err)
+			}
+			if err = bucket.Delete([]byte(word)); err != nil {
+				return fmt.Errorf("Failed to migrate '%s': '%w'", word, err)
 			}
 		}
 		return nil
 	})
+}
+
+// Words live in BUCKET_VOCABULARY/<lang>/<word>, one nested bucket per language.
+// lang_bucket returns nil (and no error) if we are in a read-only tx and the bucket doesn't exist yet.
+func lang_bucket(tx *bolt.Tx, lang string) (*bolt.Bucket, error) {
+	if lang == "" {
+		return nil, EmptyLanguageError
+	}
+	if !tx.Writable() {
+		vocab := tx.Bucket(BUCKET_VOCABULARY)
+		if vocab == nil {
+			return nil, nil
+		}
+		return vocab.Bucket([]byte(lang)), nil
+	}
+	vocab, err := tx.CreateBucketIfNotExists(BUCKET_VOCABULARY)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create bucket: %w", err)
+	}
+	bucket, err := vocab.CreateBucketIfNotExists([]byte(lang))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create bucket '%s': %w", lang, err)
+	}
+	return bucket, nil
+}
+
+func DBLanguages(db *bolt.DB) ([]string, error) {
+	var result []string
+	err := db.View(func(tx *bolt.Tx) error {
+		vocab := tx.Bucket(BUCKET_VOCABULARY)
+		if vocab == nil {
+			return nil
+		}
+		return vocab.ForEach(func(k, v []byte) error {
+			if v == nil { // nested buckets have nil values
+				result = append(result, string(k))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return result, fmt.Errorf("bbolt db.View in DBLanguages failed '%w'", err)
+	}
+	return result, nil
+}
+
+// record_has_context is true if the encoded record has a context, without decoding all of it.
+// NOTE: "context" is omitempty, and in a JSON string the quotes would be escaped
+func record_has_context(data []byte) bool {
+	return bytes.Contains(data, []byte(`"context":`))
+}
+
+// DBInit adds the words of a text, existing words are only given a context if they don't have one yet.
+// NOTE: it runs every time the text window moves, so existing words are only decoded when they get a context
+func DBInit(db *bolt.DB, lang string, mk map[string]WordContext) error {
+	now := time.Now()
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket, err := lang_bucket(tx, lang)
+		if err != nil {
+			return err
+		}
+		var added []string
+		for k, ctx := range mk {
+			rec := NewWordRecord(now)
+			if data := bucket.Get([]byte(k)); data != nil { // don't override key/val if exists
+				if ctx.Sentence == "" || record_has_context(data) {
+					continue
+				}
+				if rec, err = DecodeWordRecord(data); err != nil {
+					fmt.Printf("[WARNING] not adding a context to '%s' in '%s': %v\n", k, lang, err)
+					continue
+				}
+			} else {
+				added = append(added, k)
+			}
+			if ctx.Sentence != "" {
+				ctx := ctx
+				rec.Context = &ctx
+			}
+			data, err := EncodeWordRecord(&rec)
+			if err != nil {
+				return err
+			}
+			if err = bucket.Put([]byte(k), data); err != nil {
+				return fmt.Errorf("Failed to insert '%s': '%w'", k, err)
+			}
+		}
+		return snapshot_add(tx, lang, added...)
+	})
 	if err != nil {
-		return fmt.Errorf("bbolt db.Update in DBInit failed '%v'", err)
+		return fmt.Errorf("bbolt db.Update in DBInit failed '%w'", err)
 	}
 	return nil
 }
 
-func DBInsert(db *bolt.DB, k string) error {
+func DBInsert(db *bolt.DB, lang string, k string) error {
 	err := db.Update(func(tx *bolt.Tx) error {
-		bucket, err := tx.CreateBucketIfNotExists([]byte("TestWords"))
+		bucket, err := lang_bucket(tx, lang)
 		if err != nil {
-			return fmt.Errorf("Failed to create bucket: %v", err)
+			return err
 		}
 		if bucket.Get([]byte(k)) == nil { // don't override key/val if exists
-			err = bucket.Put([]byte(k), []byte(""))
+			rec := NewWordRecord(time.Now())
+			data, err := EncodeWordRecord(&rec)
 			if err != nil {
-				return fmt.Errorf("Failed to insert '%s': '%v'", k, err)
+				return err
 			}
+			err = bucket.Put([]byte(k), data)
+			if err != nil {
+				return fmt.Errorf("Failed to insert '%s': '%w'", k, err)
+			}
+			return snapshot_add(tx, lang, k)
 		}
 		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("bbolt db.Update in DBInsert failed '%v'", err)
+		return fmt.Errorf("bbolt db.Update in DBInsert failed '%w'", err)
 	}
 	return nil
 }
 
-func DBView(db *bolt.DB, find string) (bool, error) {
+func DBView(db *bolt.DB, lang string, find string) (bool, error) {
 	var result bool
 	err := db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte("TestWords"))
+		bucket, err := lang_bucket(tx, lang)
+		if err != nil {
+			return err
+		}
 		if bucket == nil {
 			return fmt.Errorf("Failed to find bucket")
 		}
@@ -76,7 +425,267 @@ func DBView(db *bolt.DB, find string) (bool, error) {
 		return nil
 	})
 	if err != nil {
-		return result, fmt.Errorf("bbolt db.View in DBView failed '%v'", err)
+		return result, fmt.Errorf("bbolt db.View in DBView failed '%w'", err)
 	}
 	return result, nil
 }
+
+func DBGetWord(db *bolt.DB, lang string, word string) (WordRecord, error) {
+	var rec WordRecord
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket, err := lang_bucket(tx, lang)
+		if err != nil {
+			return err
+		}
+		if bucket == nil {
+			return WordNotFoundError
+		}
+		data := bucket.Get([]byte(word))
+		if data == nil {
+			return WordNotFoundError
+		}
+		rec, err = DecodeWordRecord(data)
+		return err
+	})
+	if err != nil {
+		return rec, fmt.Errorf("bbolt db.View in DBGetWord failed '%w'", err)
+	}
+	return rec, nil
+}
+
+// DBPutWord overwrites whatever was stored for word.
+func DBPutWord(db *bolt.DB, lang string, word string, rec WordRecord) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket, err := lang_bucket(tx, lang)
+		if err != nil {
+			return err
+		}
+		data, err := EncodeWordRecord(&rec)
+		if err != nil {
+			return err
+		}
+		added := bucket.Get([]byte(word)) == nil
+		if err = bucket.Put([]byte(word), data); err != nil {
+			return fmt.Errorf("Failed to insert '%s': '%w'", word, err)
+		}
+		if added {
+			return snapshot_add(tx, lang, word)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("bbolt db.Update in DBPutWord failed '%w'", err)
+	}
+	return nil
+}
+
+// DBUpdateWord runs fn on the stored record (or on a fresh one if the word is missing)
+// and writes the result back in the same transaction.
+func DBUpdateWord(db *bolt.DB, lang string, word string, fn func(rec *WordRecord) error) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket, err := lang_bucket(tx, lang)
+		if err != nil {
+			return err
+		}
+		rec := NewWordRecord(time.Now())
+		old := bucket.Get([]byte(word))
+		if len(old) > 0 {
+			if rec, err = DecodeWordRecord(old); err != nil {
+				return err
+			}
+		}
+		if err = fn(&rec); err != nil {
+			return err
+		}
+		data, err := EncodeWordRecord(&rec)
+		if err != nil {
+			return err
+		}
+		if err = bucket.Put([]byte(word), data); err != nil {
+			return fmt.Errorf("Failed to update '%s': '%w'", word, err)
+		}
+		if old == nil {
+			return snapshot_add(tx, lang, word)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("bbolt db.Update in DBUpdateWord failed '%w'", err)
+	}
+	return nil
+}
+
+// DBForEachWord calls fn for every word of lang in key order, fn must not write to the db.
+func DBForEachWord(db *bolt.DB, lang string, fn func(word string, rec WordRecord) error) error {
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket, err := lang_bucket(tx, lang)
+		if err != nil || bucket == nil {
+			return err
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			rec, err := DecodeWordRecord(v)
+			if err != nil {
+				return fmt.Errorf("Failed to decode '%s': '%w'", k, err)
+			}
+			return fn(string(k), rec)
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("bbolt db.View in DBForEachWord failed '%w'", err)
+	}
+	return nil
+}
+
+// DBFillTrie inserts every word of lang into trie.
+func DBFillTrie(db *bolt.DB, lang string, trie *TrieNode) error {
+	return DBForEachWord(db, lang, func(word string, rec WordRecord) error {
+		trie.Insert(word)
+		return nil
+	})
+}
+
+// DBGetStatuses looks up a whole page of words in one transaction.
+// Words that aren't in the db are left out of the result, callers treat them as WORD_STATUS_NEW.
+func DBGetStatuses(db *bolt.DB, lang string, words []string) (map[string]WordStatus, error) {
+	result := make(map[string]WordStatus, len(words))
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket, err := lang_bucket(tx, lang)
+		if err != nil || bucket == nil {
+			return err
+		}
+		for _, word := range words {
+			if _, ok := result[word]; ok || word == "" {
+				continue
+			}
+			data := bucket.Get([]byte(word))
+			if len(data) == 0 {
+				continue
+			}
+			rec, err := DecodeWordRecord(data)
+			if err != nil {
+				return fmt.Errorf("Failed to decode '%s': '%w'", word, err)
+			}
+			result[word] = rec.Status
+		}
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("bbolt db.View in DBGetStatuses failed '%w'", err)
+	}
+	return result, nil
+}
+
+// DBMergeWords merges words into lang in one transaction, see MergeWordRecord.
+// Returns the number of words that were new or changed.
+func DBMergeWords(db *bolt.DB, lang string, words map[string]WordRecord) (int, error) {
+	merged := 0
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket, err := lang_bucket(tx, lang)
+		if err != nil {
+			return err
+		}
+		var added []string
+		for word, src := range words {
+			rec := src
+			old := bucket.Get([]byte(word))
+			if old != nil {
+				if rec, err = DecodeWordRecord(old); err != nil {
+					return fmt.Errorf("Failed to decode '%s': '%w'", word, err)
+				}
+				MergeWordRecord(&rec, src)
+			}
+			if rec.FirstSeen.IsZero() {
+				rec.FirstSeen = time.Now()
+				rec.LastSeen = rec.FirstSeen
+			}
+			data, err := EncodeWordRecord(&rec)
+			if err != nil {
+				return err
+			}
+			if bytes.Equal(data, old) {
+				continue
+			}
+			if err = bucket.Put([]byte(word), data); err != nil {
+				return fmt.Errorf("Failed to merge '%s': '%w'", word, err)
+			}
+			if old == nil {
+				added = append(added, word)
+			}
+			merged += 1
+		}
+		return snapshot_add(tx, lang, added...)
+	})
+	if err != nil {
+		return merged, fmt.Errorf("bbolt db.Update in DBMergeWords failed '%w'", err)
+	}
+	return merged, nil
+}
+
+// DBImportTestWords copies the old single-language BUCKET_TEST_WORDS into lang.
+// Words that lang already has are kept as they are. Returns the number of imported words.
+func DBImportTestWords(db *bolt.DB, lang string) (int, error) {
+	imported := 0
+	err := db.Update(func(tx *bolt.Tx) error {
+		src := tx.Bucket(BUCKET_TEST_WORDS)
+		if src == nil {
+			return nil
+		}
+		dst, err := lang_bucket(tx, lang)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		var added []string
+		err = src.ForEach(func(k, v []byte) error {
+			k = []byte(FoldWord(string(k)))
+			if old := dst.Get(k); old != nil {
+				// NOTE: several legacy keys can fold to the same word, merge them without overriding what's known
+				if len(v) == 0 { // not migrated yet, nothing to merge
+					return nil
+				}
+				return merge_test_word(dst, k, old, v)
+			}
+			if len(v) == 0 { // not migrated yet
+				rec := NewWordRecord(now)
+				if v, err = EncodeWordRecord(&rec); err != nil {
+					return err
+				}
+			}
+			if err := dst.Put(k, v); err != nil {
+				return fmt.Errorf("Failed to import '%s': '%w'", k, err)
+			}
+			added = append(added, string(k))
+			imported += 1
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return snapshot_add(tx, lang, added...)
+	})
+	if err != nil {
+		return imported, fmt.Errorf("bbolt db.Update in DBImportTestWords failed '%w'", err)
+	}
+	return imported, nil
+}
+
+// merge_test_word merges the legacy record v into the record old that's already stored under k.
+func merge_test_word(dst *bolt.Bucket, k, old, v []byte) error {
+	rec, err := DecodeWordRecord(old)
+	if err != nil {
+		return fmt.Errorf("Failed to decode '%s': '%w'", k, err)
+	}
+	src, err := DecodeWordRecord(v)
+	if err != nil {
+		return fmt.Errorf("Failed to decode '%s': '%w'", k, err)
+	}
+	MergeWordRecord(&rec, src)
+	data, err := EncodeWordRecord(&rec)
+	if err != nil {
+		return err
+	}
+	if err := dst.Put(k, data); err != nil {
+		return fmt.Errorf("Failed to import '%s': '%w'", k, err)
+	}
+	return nil
+}
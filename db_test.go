@@ -0,0 +1,340 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestDB(t *testing.T) *bolt.DB {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), FILE_MODE_RW, nil)
+	if err != nil {
+		t.Fatalf("openTestDB failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestWordRecordRoundTrip(t *testing.T) {
+	now := time.Date(2022, 11, 5, 10, 0, 0, 0, time.UTC)
+	in := WordRecord{Status: WORD_STATUS_LEARNING, FirstSeen: now, LastSeen: now.Add(time.Hour), Lookups: 3, Note: "la maison"}
+	data, err := EncodeWordRecord(&in)
+	if err != nil {
+		t.Fatalf("EncodeWordRecord failed: %v", err)
+	}
+	if data[0] != WORD_RECORD_VERSION {
+		t.Errorf("EncodeWordRecord failed: got version (%d) expected (%d)", data[0], WORD_RECORD_VERSION)
+	}
+	out, err := DecodeWordRecord(data)
+	if err != nil {
+		t.Fatalf("DecodeWordRecord failed: %v", err)
+	}
+	if out != in {
+		t.Errorf("TestWordRecordRoundTrip failed: got (%+v) expected (%+v)", out, in)
+	}
+	if _, err := DecodeWordRecord([]byte("")); err != LegacyRecordError {
+		t.Errorf("DecodeWordRecord failed: got (%v) expected (%v)", err, LegacyRecordError)
+	}
+	if out, err := DecodeWordRecord(append([]byte{WORD_RECORD_VERSION}, `{"status":200}`...)); err != nil || out.Status != WORD_STATUS_NEW {
+		t.Errorf("DecodeWordRecord failed on an unknown status: got (%v, %v) expected (%v)", out.Status, err, WORD_STATUS_NEW)
+	}
+}
+
+func TestDBMigrate(t *testing.T) {
+	db := openTestDB(t)
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket(BUCKET_TEST_WORDS)
+		if err != nil {
+			return err
+		}
+		bucket.Put([]byte("hobbit"), []byte(""))
+		return bucket.Put([]byte("maison"), []byte(""))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DBMigrate(db); err != nil {
+		t.Fatalf("DBMigrate failed: %v", err)
+	}
+	db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(BUCKET_TEST_WORDS).ForEach(func(k, v []byte) error {
+			rec, err := DecodeWordRecord(v)
+			if err != nil || rec.Status != WORD_STATUS_NEW || rec.FirstSeen.IsZero() {
+				t.Errorf("TestDBMigrate failed: (%s) got (%+v, %v)", k, rec, err)
+			}
+			return nil
+		})
+	})
+	// running it twice must be a no-op
+	if err := DBMigrate(db); err != nil {
+		t.Fatalf("DBMigrate failed on second run: %v", err)
+	}
+}
+
+func TestDBMigrateFoldKeys(t *testing.T) {
+	db := openTestDB(t)
+	for word, rec := range map[string]WordRecord{
+		"maison": {Status: WORD_STATUS_NEW, Note: "house"},
+		"Maison": {Status: WORD_STATUS_KNOWN, Note: "the house"},
+		"Chat":   {Status: WORD_STATUS_LEARNING},
+	} {
+		if err := DBPutWord(db, "fr", word, rec); err != nil {
+			t.Fatalf("DBPutWord failed: %v", err)
+		}
+	}
+	// pretend this db is from before the keys were folded
+	db.Update(func(tx *bolt.Tx) error {
+		meta, _ := tx.CreateBucketIfNotExists(BUCKET_META)
+		return meta.Put(KEY_SCHEMA, []byte{1})
+	})
+
+	if err := DBMigrate(db); err != nil {
+		t.Fatalf("DBMigrate failed: %v", err)
+	}
+	words := make(map[string]WordRecord)
+	DBForEachWord(db, "fr", func(word string, rec WordRecord) error {
+		words[word] = rec
+		return nil
+	})
+	if len(words) != 2 || words["maison"].Note != "house" || words["maison"].Status != WORD_STATUS_KNOWN || words["chat"].Status != WORD_STATUS_LEARNING {
+		t.Errorf("TestDBMigrateFoldKeys failed: got (%+v)", words)
+	}
+}
+
+func TestDBGetPutUpdateWord(t *testing.T) {
+	db := openTestDB(t)
+
+	if _, err := DBGetWord(db, "fr", "hobbit"); !errors.Is(err, WordNotFoundError) {
+		t.Errorf("DBGetWord failed: got (%v) expected (%v)", err, WordNotFoundError)
+	}
+	// every error out of the db functions is wrapped with %w
+	if err := DBInit(db, "", map[string]WordContext{"hobbit": {}}); !errors.Is(err, EmptyLanguageError) {
+		t.Errorf("DBInit failed: got (%v) expected (%v)", err, EmptyLanguageError)
+	}
+	if _, err := DBMergeWords(db, "", nil); !errors.Is(err, EmptyLanguageError) {
+		t.Errorf("DBMergeWords failed: got (%v) expected (%v)", err, EmptyLanguageError)
+	}
+	if err := DBInit(db, "fr", map[string]WordContext{"hobbit": {}, "bilbo": {}}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := DBUpdateWord(db, "fr", "hobbit", func(rec *WordRecord) error {
+		rec.Status = WORD_STATUS_KNOWN
+		rec.Lookups += 1
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("DBUpdateWord failed: %v", err)
+	}
+	rec, err := DBGetWord(db, "fr", "hobbit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Status != WORD_STATUS_KNOWN || rec.Lookups != 1 {
+		t.Errorf("DBUpdateWord failed: got (%+v)", rec)
+	}
+
+	// DBInit and DBInsert must not override existing values
+	DBInit(db, "fr", map[string]WordContext{"hobbit": {}})
+	DBInsert(db, "fr", "hobbit")
+	if rec, _ = DBGetWord(db, "fr", "hobbit"); rec.Status != WORD_STATUS_KNOWN {
+		t.Errorf("DBInsert overrode an existing word: got (%+v)", rec)
+	}
+
+	rec.Note = "a halfling"
+	if err := DBPutWord(db, "fr", "bilbo", rec); err != nil {
+		t.Fatalf("DBPutWord failed: %v", err)
+	}
+	if got, _ := DBGetWord(db, "fr", "bilbo"); got.Note != "a halfling" {
+		t.Errorf("DBPutWord failed: got (%+v)", got)
+	}
+}
+
+func TestDBGetStatuses(t *testing.T) {
+	db := openTestDB(t)
+
+	if status, err := DBGetStatuses(db, "fr", []string{"hobbit"}); err != nil || len(status) != 0 {
+		t.Errorf("DBGetStatuses on an empty db failed: got (%v, %v)", status, err)
+	}
+
+	DBInit(db, "fr", map[string]WordContext{"hobbit": {}, "bilbo": {}})
+	DBUpdateWord(db, "fr", "bilbo", func(rec *WordRecord) error {
+		rec.Status = WORD_STATUS_LEARNING
+		return nil
+	})
+
+	status, err := DBGetStatuses(db, "fr", []string{"hobbit", "bilbo", "bilbo", "", "gandalf"})
+	if err != nil {
+		t.Fatalf("DBGetStatuses failed: %v", err)
+	}
+	want := map[string]WordStatus{"hobbit": WORD_STATUS_NEW, "bilbo": WORD_STATUS_LEARNING}
+	if len(status) != len(want) {
+		t.Errorf("DBGetStatuses failed: got (%v) expected (%v)", status, want)
+	}
+	for k, v := range want {
+		if status[k] != v {
+			t.Errorf("DBGetStatuses failed: (%s) got (%s) expected (%s)", k, status[k], v)
+		}
+	}
+}
+
+func TestNextWordStatus(t *testing.T) {
+	status := WORD_STATUS_NEW
+	for _, want := range []WordStatus{WORD_STATUS_LEARNING, WORD_STATUS_KNOWN, WORD_STATUS_NEW} {
+		if status = NextWordStatus(status); status != want {
+			t.Errorf("NextWordStatus failed: got (%s) expected (%s)", status, want)
+		}
+	}
+	if status = NextWordStatus(WORD_STATUS_IGNORED); status != WORD_STATUS_NEW {
+		t.Errorf("NextWordStatus failed: got (%s) expected (%s)", status, WORD_STATUS_NEW)
+	}
+}
+
+func TestDBLanguagesAreSeparate(t *testing.T) {
+	db := openTestDB(t)
+
+	DBInit(db, "fr", map[string]WordContext{"maison": {}})
+	DBInit(db, "zh", map[string]WordContext{"房子": {}})
+
+	if found, _ := DBView(db, "fr", "房子"); found {
+		t.Errorf("TestDBLanguagesAreSeparate failed: found a 'zh' word in 'fr'")
+	}
+	if found, _ := DBView(db, "zh", "房子"); !found {
+		t.Errorf("TestDBLanguagesAreSeparate failed: didn't find a 'zh' word in 'zh'")
+	}
+	langs, err := DBLanguages(db)
+	if err != nil || len(langs) != 2 || langs[0] != "fr" || langs[1] != "zh" {
+		t.Errorf("DBLanguages failed: got (%v, %v)", langs, err)
+	}
+	if err := DBInsert(db, "", "maison"); err == nil {
+		t.Errorf("DBInsert with an empty language should fail")
+	}
+}
+
+func TestDBImportTestWords(t *testing.T) {
+	db := openTestDB(t)
+	db.Update(func(tx *bolt.Tx) error {
+		bucket, _ := tx.CreateBucket(BUCKET_TEST_WORDS)
+		bucket.Put([]byte("hobbit"), []byte(""))
+		return bucket.Put([]byte("maison"), []byte(""))
+	})
+	DBUpdateWord(db, "fr", "maison", func(rec *WordRecord) error {
+		rec.Status = WORD_STATUS_KNOWN
+		return nil
+	})
+
+	imported, err := DBImportTestWords(db, "fr")
+	if err != nil || imported != 1 {
+		t.Errorf("DBImportTestWords failed: got (%d, %v) expected (1, nil)", imported, err)
+	}
+	if rec, err := DBGetWord(db, "fr", "hobbit"); err != nil || rec.Status != WORD_STATUS_NEW {
+		t.Errorf("DBImportTestWords failed: 'hobbit' got (%+v, %v)", rec, err)
+	}
+	if rec, _ := DBGetWord(db, "fr", "maison"); rec.Status != WORD_STATUS_KNOWN {
+		t.Errorf("DBImportTestWords overrode an existing word: got (%+v)", rec)
+	}
+}
+
+func TestDBImportTestWordsFolded(t *testing.T) {
+	db := openTestDB(t)
+	learning := NewWordRecord(time.Unix(1000, 0))
+	learning.Status = WORD_STATUS_LEARNING
+	learning.Note = "man"
+	data, err := EncodeWordRecord(&learning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Update(func(tx *bolt.Tx) error {
+		bucket, _ := tx.CreateBucket(BUCKET_TEST_WORDS)
+		bucket.Put([]byte("Homme"), []byte(""))
+		return bucket.Put([]byte("homme"), data)
+	})
+
+	imported, err := DBImportTestWords(db, "fr")
+	if err != nil || imported != 1 {
+		t.Errorf("DBImportTestWords failed: got (%d, %v) expected (1, nil)", imported, err)
+	}
+	rec, err := DBGetWord(db, "fr", "homme")
+	if err != nil || rec.Status != WORD_STATUS_LEARNING || rec.Note != "man" || !rec.FirstSeen.Equal(learning.FirstSeen) {
+		t.Errorf("DBImportTestWords failed to merge 'Homme' and 'homme': got (%+v, %v) expected (%+v)", rec, err, learning)
+	}
+}
+
+func TestDBOpenLockTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "my.db")
+	db, err := DBOpen(path, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("DBOpen failed: %v", err)
+	}
+	defer db.Close()
+
+	start := time.Now()
+	second, err := DBOpen(path, 50*time.Millisecond)
+	if err == nil {
+		second.Close()
+		t.Fatalf("DBOpen on a locked db should fail")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("DBOpen didn't respect the timeout, took %v", time.Since(start))
+	}
+}
+
+func TestDBInitStoresContext(t *testing.T) {
+	db := openTestDB(t)
+	DBInsert(db, "fr", "maison")
+	DBUpdateWord(db, "fr", "maison", func(rec *WordRecord) error {
+		rec.Status = WORD_STATUS_KNOWN
+		return nil
+	})
+
+	first := WordContext{File: "French.txt", Offset: 4, Sentence: "La maison est grande."}
+	DBInit(db, "fr", map[string]WordContext{"maison": first, "grande": {File: "French.txt", Offset: 15, Sentence: first.Sentence}})
+	DBInit(db, "fr", map[string]WordContext{"maison": {File: "Other.txt", Sentence: "Une autre maison."}})
+
+	rec, err := DBGetWord(db, "fr", "maison")
+	if err != nil || rec.Context == nil || *rec.Context != first || rec.Status != WORD_STATUS_KNOWN {
+		t.Errorf("DBInit failed: 'maison' got (%+v, %v)", rec, err)
+	}
+	if rec, _ = DBGetWord(db, "fr", "grande"); rec.Context == nil || rec.Context.Offset != 15 {
+		t.Errorf("DBInit failed: 'grande' got (%+v)", rec)
+	}
+}
+
+func TestDBInitBrokenRecord(t *testing.T) {
+	db := openTestDB(t)
+	DBPutWord(db, "fr", "chat", WordRecord{Note: `a "context": that isn't one`})
+	db.Update(func(tx *bolt.Tx) error {
+		bucket, _ := lang_bucket(tx, "fr")
+		return bucket.Put([]byte("chien"), []byte{WORD_RECORD_VERSION, '{'})
+	})
+
+	ctx := WordContext{File: "French.txt", Sentence: "Le chat et le chien."}
+	if err := DBInit(db, "fr", map[string]WordContext{"chat": ctx, "chien": ctx}); err != nil {
+		t.Errorf("DBInit failed on a broken record: %v", err)
+	}
+	if rec, err := DBGetWord(db, "fr", "chat"); err != nil || rec.Context == nil || *rec.Context != ctx {
+		t.Errorf("DBInit failed: 'chat' got (%+v, %v)", rec, err)
+	}
+	if _, err := DBGetWord(db, "fr", "chien"); err == nil {
+		t.Errorf("DBInit failed: the broken 'chien' record should be left alone")
+	}
+}
+
+func TestDBFillTrie(t *testing.T) {
+	db := openTestDB(t)
+	DBInit(db, "fr", map[string]WordContext{"maison": {}, "main": {}, "chat": {}})
+	DBInit(db, "de", map[string]WordContext{"haus": {}})
+
+	trie := NewTrie()
+	trie.Insert("rules")
+	if err := DBFillTrie(db, "fr", trie); err != nil {
+		t.Fatalf("DBFillTrie failed: %v", err)
+	}
+	if trie.Len() != 4 || !trie.Search("maison") || trie.Search("haus") {
+		t.Errorf("DBFillTrie failed: got (%v) expected (chat main maison rules)", trie.KeysWithPrefix("", 10))
+	}
+}
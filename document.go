@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A Document is a text that we don't read all at once. UTF-8 files are memory mapped (see map_file),
+// so the OS pages in what we look at and drops it again, other encodings are decoded into memory.
+// Lines are found lazily, we only remember where every LINE_CHECKPOINT-th line starts,
+// that's 8 bytes per thousand lines however big the file is.
+//
+// A TextWindow is the part of the document that is wrapped and shown, WINDOW_LINES lines of it.
+// Everything that used to work on the whole text (test_tokens, linemeta, the known words) works on the window,
+// and the window slides along when the page gets close to one of its ends.
+
+const LINE_CHECKPOINT = 1024
+
+// NOTE: most texts are shorter than this, they fit in one window and never slide
+const WINDOW_LINES = 2000
+
+type Document struct {
+	path        string
+	data        []byte
+	unmap       func() error
+	checkpoints []int // checkpoints[i] is where line i*LINE_CHECKPOINT starts
+	indexed     bool  // the checkpoints go all the way to the end
+	chapters    []Chapter
+	cues        []Cue         // cues[i] is line i, only for subtitles
+	spans       [][]StyleSpan // spans[i] are the styles of line i, only for Markdown
+}
+
+// OpenDocument opens path, encoding works like it does for DecodeText.
+// An EPUB is turned into text first, see LoadEPUB, subtitles become one line per cue
+// and Markdown loses its markup (see ParseMarkdown).
+func OpenDocument(path string, encoding string) (*Document, error) {
+	if is_epub(path) {
+		book, err := LoadEPUB(path)
+		if err != nil {
+			return nil, err
+		}
+		return &Document{path: path, data: []byte(book.Text), checkpoints: []int{0}, chapters: book.Chapters}, nil
+	}
+	if is_subtitles(path) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		text, err := DecodeText(data, encoding)
+		if err != nil {
+			return nil, fmt.Errorf("'%s': %v", path, err)
+		}
+		cues, err := ParseSubtitles(path, text)
+		if err != nil {
+			return nil, fmt.Errorf("'%s': %v", path, err)
+		}
+		return &Document{path: path, data: []byte(CueText(cues)), checkpoints: []int{0}, cues: cues}, nil
+	}
+	if is_markdown(path) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		text, err := DecodeText(data, encoding)
+		if err != nil {
+			return nil, fmt.Errorf("'%s': %v", path, err)
+		}
+		lines, spans := ParseMarkdown(text)
+		return &Document{path: path, data: []byte(strings.Join(lines, "\n")), checkpoints: []int{0}, spans: spans}, nil
+	}
+	data, unmap, err := map_file(path)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{path: path, data: data, unmap: unmap, checkpoints: []int{0}}
+	guess, bom := SniffEncoding(data)
+	if bom == 0 && encoding != "" {
+		guess = strings.ToLower(encoding)
+	}
+	if guess == "utf-8" {
+		doc.data = data[bom:]
+		return doc, nil
+	}
+	// NOTE: only UTF-8 can be used straight from the mapped file
+	text, err := DecodeText(data, encoding)
+	doc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("'%s': %v", path, err)
+	}
+	doc.data = []byte(text)
+	return doc, nil
+}
+
+func (doc *Document) Close() error {
+	doc.data = nil
+	if doc.unmap == nil {
+		return nil
+	}
+	err := doc.unmap()
+	doc.unmap = nil
+	return err
+}
+
+// Size is the size of the text in bytes.
+func (doc *Document) Size() int {
+	return len(doc.data)
+}
+
+// index adds checkpoints until line n has one (or the text ends).
+func (doc *Document) index(n int) {
+	for !doc.indexed && len(doc.checkpoints) <= n/LINE_CHECKPOINT {
+		offset := doc.checkpoints[len(doc.checkpoints)-1]
+		for i := 0; i < LINE_CHECKPOINT; i++ {
+			next := bytes.IndexByte(doc.data[offset:], '\n')
+			if next < 0 {
+				doc.indexed = true
+				return
+			}
+			offset += next + 1
+		}
+		doc.checkpoints = append(doc.checkpoints, offset)
+	}
+}
+
+// LineStart returns where line n starts, false if the text has fewer lines.
+// Lines are split on '\n' like strings.Split does, so "a\n" has two lines.
+func (doc *Document) LineStart(n int) (int, bool) {
+	if n < 0 {
+		return 0, false
+	}
+	doc.index(n)
+	if n/LINE_CHECKPOINT >= len(doc.checkpoints) {
+		return 0, false
+	}
+	offset := doc.checkpoints[n/LINE_CHECKPOINT]
+	for i := 0; i < n%LINE_CHECKPOINT; i++ {
+		next := bytes.IndexByte(doc.data[offset:], '\n')
+		if next < 0 {
+			return 0, false
+		}
+		offset += next + 1
+	}
+	return offset, true
+}
+
+// LineCount reads the whole text, it's only for when we really need to know.
+func (doc *Document) LineCount() int {
+	doc.index(int(^uint(0) >> 1))
+	last := (len(doc.checkpoints) - 1) * LINE_CHECKPOINT
+	return last + bytes.Count(doc.data[doc.checkpoints[len(doc.checkpoints)-1]:], []byte{'\n'}) + 1
+}
+
+// Lines returns up to n lines from first, without the '\n' (or "\r\n").
+// NOTE: SniffEncoding only looks at the start of a mapped file, bad UTF-8 after that becomes U+FFFD here
+func (doc *Document) Lines(first int, n int) []string {
+	offset, ok := doc.LineStart(first)
+	if !ok {
+		return nil
+	}
+	var result []string
+	for len(result) < n {
+		end := bytes.IndexByte(doc.data[offset:], '\n')
+		if end < 0 {
+			result = append(result, strings.ToValidUTF8(string(bytes.TrimSuffix(doc.data[offset:], []byte{'\r'})), "\uFFFD"))
+			break
+		}
+		result = append(result, strings.ToValidUTF8(string(bytes.TrimSuffix(doc.data[offset:offset+end], []byte{'\r'})), "\uFFFD"))
+		offset += end + 1
+	}
+	return result
+}
+
+type TextWindow struct {
+	doc    *Document
+	length int // what the lines are wrapped to, see NewWrapLines
+	font_w int
+	first  int           // the first document line in the window
+	count  int           // how many document lines are in it
+	lines  []string      // the wrapped lines, test_tokens
+	source []int         // the document line of every wrapped line
+	runs   [][]StyledRun // the styles of every wrapped line, nil if it has none
+}
+
+func NewTextWindow(doc *Document, length int, font_w int) *TextWindow {
+	tw := &TextWindow{doc: doc, length: length, font_w: font_w}
+	tw.Load(0)
+	return tw
+}
+
+// Load wraps WINDOW_LINES document lines from first.
+// NOTE: every line is wrapped on its own, so a line wraps the same way in every window it's in
+func (tw *TextWindow) Load(first int) {
+	tw.lines = tw.lines[:0]
+	tw.source = tw.source[:0]
+	tw.runs = tw.runs[:0]
+	raw := tw.doc.Lines(first, WINDOW_LINES)
+	for i, line := range raw {
+		var spans []StyleSpan
+		if first+i < len(tw.doc.spans) {
+			spans = tw.doc.spans[first+i]
+		}
+		offset := 0
+		for _, wrapped := range NewWrapLines(line, tw.length, tw.font_w) {
+			tw.lines = append(tw.lines, wrapped)
+			tw.source = append(tw.source, first+i)
+			// NOTE: the wrapped lines are the line cut into pieces, nothing is left out
+			if at := strings.Index(line[offset:], wrapped); spans != nil && at >= 0 {
+				offset += at
+				tw.runs = append(tw.runs, StyledRuns(wrapped, offset, spans))
+				offset += len(wrapped)
+			} else {
+				tw.runs = append(tw.runs, nil)
+			}
+		}
+	}
+	tw.first = first
+	tw.count = len(raw)
+}
+
+// Wrap wraps the same lines again, for when the font changed.
+func (tw *TextWindow) Wrap(length int, font_w int) {
+	tw.length = length
+	tw.font_w = font_w
+	tw.Load(tw.first)
+}
+
+func (tw *TextWindow) AtStart() bool {
+	return tw.first == 0
+}
+
+func (tw *TextWindow) AtEnd() bool {
+	_, more := tw.doc.LineStart(tw.first + tw.count)
+	return !more
+}
+
+// Slide moves the window when the page [start, start+margin) or the margin before it isn't in it anymore,
+// so that the document line at start ends up in the middle. It returns where start is in the new window.
+func (tw *TextWindow) Slide(start int, margin int) (int, bool) {
+	if start < 0 || start >= len(tw.lines) {
+		return start, false
+	}
+	near_end := start+margin > len(tw.lines) && !tw.AtEnd()
+	near_start := start-margin < 0 && !tw.AtStart()
+	if !near_end && !near_start {
+		return start, false
+	}
+	line := tw.source[start]
+	skip := start - sort.SearchInts(tw.source, line) // start can be in the middle of a wrapped line
+
+	first := line - WINDOW_LINES/2
+	if first < 0 {
+		first = 0
+	}
+	if first == tw.first {
+		return start, false
+	}
+	tw.Load(first)
+	return sort.SearchInts(tw.source, line) + skip, true
+}
+
+// Seek returns where document line n starts in the window, the window is loaded around n
+// if it isn't in there (that's the true).
+func (tw *TextWindow) Seek(n int) (int, bool) {
+	moved := false
+	if n < tw.first || n >= tw.first+tw.count {
+		first := n - WINDOW_LINES/2
+		if first < 0 {
+			first = 0
+		}
+		tw.Load(first)
+		moved = true
+	}
+	return sort.SearchInts(tw.source, n), moved
+}
+
+// Line is the document line the wrapped line i comes from.
+func (tw *TextWindow) Line(i int) int {
+	if i < 0 || len(tw.source) == 0 {
+		return tw.first
+	}
+	if i >= len(tw.source) {
+		return tw.source[len(tw.source)-1]
+	}
+	return tw.source[i]
+}
+
+// SetLineData gives every line of linemeta (made from tw.lines) the cue it comes from and its styles.
+func (tw *TextWindow) SetLineData(linemeta []LineMetaData) {
+	for i := 0; i < len(linemeta) && i < len(tw.source); i++ {
+		linemeta[i].cue = nil
+		if tw.source[i] < len(tw.doc.cues) {
+			linemeta[i].cue = &tw.doc.cues[tw.source[i]]
+		}
+		linemeta[i].runs = tw.runs[i]
+	}
+}
+
+// Position is how far into the document the wrapped line i is, in bytes out of the whole size.
+func (tw *TextWindow) Position(i int) (int, int) {
+	if i >= len(tw.source) {
+		if tw.AtEnd() {
+			return tw.doc.Size(), tw.doc.Size()
+		}
+		i = len(tw.source) - 1
+	}
+	if i < 0 {
+		return 0, tw.doc.Size()
+	}
+	offset, _ := tw.doc.LineStart(tw.source[i])
+	return offset, tw.doc.Size()
+}
+
+// UniqueWords is GetUniqueWordsWithContext of the document lines in the window,
+// the offsets are in the whole document.
+func (tw *TextWindow) UniqueWords(filename string) map[string]WordContext {
+	base, _ := tw.doc.LineStart(tw.first)
+	text := strings.Join(tw.doc.Lines(tw.first, tw.count), "\n")
+	words := GetUniqueWordsWithContext(filename, text)
+	for k, ctx := range words {
+		ctx.Offset += base
+		words[k] = ctx
+	}
+	return words
+}
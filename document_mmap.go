@@ -0,0 +1,34 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// map_file maps path read-only, unmap has to be called once the data isn't used anymore.
+func map_file(path string) (data []byte, unmap func() error, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := stat.Size()
+	if size == 0 { // NOTE: mmap fails on an empty file
+		return nil, func() error { return nil }, nil
+	}
+	if int64(int(size)) != size {
+		return nil, nil, fmt.Errorf("'%s' is too big to map (%d bytes)", path, size)
+	}
+	data, err = syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to mmap '%s': %v", path, err)
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// NOTE: without mmap the whole file is read into memory when it's opened, a 500 MB text takes 500 MB.
+// Only the wrapping stays bounded (see TextWindow), the memory a Document needs doesn't.
+
+// map_file reads the whole file where we don't have mmap.
+func map_file(path string) (data []byte, unmap func() error, err error) {
+	data, err = os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
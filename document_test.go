@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func write_document(t testing.TB, data string) string {
+	path := t.TempDir() + "/doc.txt"
+	if err := os.WriteFile(path, []byte(data), FILE_MODE_RW); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func numbered_lines(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d of the text", i)
+	}
+	return lines
+}
+
+func TestDocumentLines(t *testing.T) {
+	lines := numbered_lines(LINE_CHECKPOINT*3 + 17)
+	doc, err := OpenDocument(write_document(t, strings.Join(lines, "\n")), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	for _, n := range []int{0, 1, LINE_CHECKPOINT - 1, LINE_CHECKPOINT, LINE_CHECKPOINT*2 + 5, len(lines) - 1} {
+		got := doc.Lines(n, 2)
+		if len(got) == 0 || got[0] != lines[n] {
+			t.Errorf("Lines(%d) failed: got (%q) expected (%q)", n, got, lines[n])
+		}
+	}
+	if got := doc.Lines(len(lines), 1); got != nil {
+		t.Errorf("Lines past the end failed: got (%q) expected (nil)", got)
+	}
+	if got := doc.LineCount(); got != len(lines) {
+		t.Errorf("LineCount failed: got (%d) expected (%d)", got, len(lines))
+	}
+	// the first lines shouldn't index the whole text
+	doc, _ = OpenDocument(write_document(t, strings.Join(lines, "\n")), "")
+	defer doc.Close()
+	doc.Lines(10, 10)
+	if doc.indexed || len(doc.checkpoints) != 1 {
+		t.Errorf("Lines indexed too much: got (%d) checkpoints", len(doc.checkpoints))
+	}
+}
+
+func TestOpenDocument(t *testing.T) {
+	tests := []struct {
+		data     string
+		encoding string
+		expected []string
+	}{
+		{"one\r\ntwo\r\n", "", []string{"one", "two", ""}},
+		{"\uFEFFone\ntwo", "", []string{"one", "two"}},
+		{"\xff\xfeo\x00n\x00e\x00\n\x00t\x00w\x00o\x00", "", []string{"one", "two"}},
+		{"\xc0 la\nfen\xeatre", "latin1", []string{"À la", "fenêtre"}},
+		{"", "", []string{""}},
+	}
+	for _, test := range tests {
+		doc, err := OpenDocument(write_document(t, test.data), test.encoding)
+		if err != nil {
+			t.Errorf("OpenDocument(%q) failed: %v", test.data, err)
+			continue
+		}
+		got := doc.Lines(0, 10)
+		if strings.Join(got, "|") != strings.Join(test.expected, "|") {
+			t.Errorf("OpenDocument(%q) failed: got (%q) expected (%q)", test.data, got, test.expected)
+		}
+		doc.Close()
+	}
+	if _, err := OpenDocument(t.TempDir()+"/missing.txt", ""); err == nil {
+		t.Errorf("OpenDocument on a missing file should fail")
+	}
+
+	// only the start is sniffed, a bad byte further on doesn't make it to the screen
+	data := strings.Repeat("a", SNIFF_UTF8_SIZE) + "\nl'\xe9t\xe9"
+	doc, err := OpenDocument(write_document(t, data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if got := doc.Lines(1, 1); len(got) != 1 || got[0] != "l'\uFFFDt\uFFFD" {
+		t.Errorf("Lines failed on bad UTF-8: got (%q) expected (%q)", got, "l'\uFFFDt\uFFFD")
+	}
+}
+
+func TestTextWindowSlide(t *testing.T) {
+	lines := numbered_lines(WINDOW_LINES * 3)
+	long := WINDOW_LINES/2 + WINDOW_LINES - 2
+	lines[long] = strings.Repeat("a long line that wraps ", 5)
+	doc, err := OpenDocument(write_document(t, strings.Join(lines, "\n")), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	tw := NewTextWindow(doc, 200, 10)
+	if tw.count != WINDOW_LINES || !tw.AtStart() || tw.AtEnd() {
+		t.Fatalf("NewTextWindow failed: got (%d) lines", tw.count)
+	}
+	if _, moved := tw.Slide(10, 30); moved {
+		t.Errorf("Slide at the start of the text shouldn't move")
+	}
+
+	// the page is on the second wrapped line of a long line at the end of the window
+	tw.Load(WINDOW_LINES / 2)
+	start := 0
+	for tw.source[start] != long {
+		start += 1
+	}
+	start += 1
+	line := tw.lines[start]
+	source := tw.source[start]
+
+	next, moved := tw.Slide(start, 30)
+	if !moved || tw.lines[next] != line || tw.source[next] != source {
+		t.Errorf("Slide failed: got (%q, %v) expected (%q)", tw.lines[next], moved, line)
+	}
+	if next-30 < 0 || next+30 > len(tw.lines) {
+		t.Errorf("Slide failed: %d isn't in the middle of %d lines", next, len(tw.lines))
+	}
+
+	tw.Load(WINDOW_LINES * 2)
+	if !tw.AtEnd() {
+		t.Errorf("AtEnd failed at the last window")
+	}
+	if _, moved := tw.Slide(len(tw.lines)-10, 30); moved {
+		t.Errorf("Slide at the end of the text shouldn't move")
+	}
+}
+
+func TestTextWindowSeekSlide(t *testing.T) {
+	lines := numbered_lines(WINDOW_LINES * 5)
+	doc, err := OpenDocument(write_document(t, strings.Join(lines, "\n")), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	tw := NewTextWindow(doc, 400, 10) // NOTE: wide enough that no line wraps
+	n := WINDOW_LINES * 5 / 2
+	jump, moved := tw.Seek(n)
+	if !moved || tw.lines[jump] != lines[n] {
+		t.Fatalf("Seek failed: got (%q, %v) expected (%q)", tw.lines[jump], moved, lines[n])
+	}
+	// NOTE: the page is at jump now, sliding from where it was before the jump (0) would load the wrong lines
+	start, moved := tw.Slide(jump, 60)
+	if moved || start != jump || tw.lines[start] != lines[n] {
+		t.Errorf("Slide after Seek failed: got (%q, %v) expected (%q)", tw.lines[start], moved, lines[n])
+	}
+}
+
+func TestTextWindowUniqueWords(t *testing.T) {
+	lines := numbered_lines(WINDOW_LINES * 2)
+	lines[WINDOW_LINES+5] = "a xylophone here"
+	data := strings.Join(lines, "\n")
+	doc, err := OpenDocument(write_document(t, data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	tw := NewTextWindow(doc, 200, 10)
+	if _, ok := tw.UniqueWords("doc.txt")["xylophone"]; ok {
+		t.Errorf("UniqueWords has a word from outside the window")
+	}
+	tw.Load(WINDOW_LINES)
+	ctx, ok := tw.UniqueWords("doc.txt")["xylophone"]
+	expected := strings.Index(data, "xylophone")
+	if !ok || ctx.Offset != expected {
+		t.Errorf("UniqueWords failed: got (%d, %v) expected (%d)", ctx.Offset, ok, expected)
+	}
+	if offset, size := tw.Position(0); size != len(data) || offset != strings.Index(data, lines[WINDOW_LINES]) {
+		t.Errorf("Position failed: got (%d, %d)", offset, size)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// An EPUB is a zip of XHTML chapters, META-INF/container.xml points to the OPF file and the spine
+// in there says in which order the chapters are read. We turn the whole book into plain text,
+// one line per paragraph, so it goes through the same wrapping as a .txt file.
+
+type Chapter struct {
+	Title string
+	Line  int // the first line of the chapter in Book.Text
+}
+
+type Book struct {
+	Title    string
+	Text     string
+	Chapters []Chapter
+}
+
+type epub_container struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epub_package struct {
+	Titles []string `xml:"metadata>title"`
+	Items  []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// the elements that end a paragraph
+var XHTML_BLOCKS = map[string]bool{
+	"p": true, "div": true, "br": true, "hr": true, "li": true, "dt": true, "dd": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"blockquote": true, "pre": true, "tr": true, "section": true, "article": true, "figcaption": true,
+}
+
+// we never want what's in these
+var XHTML_SKIP = map[string]bool{"script": true, "style": true, "svg": true, "math": true}
+
+func read_zip_file(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+	return nil, fmt.Errorf("'%s' is missing", name)
+}
+
+// XHTMLText returns the paragraphs of an XHTML chapter and its title, which is its first heading
+// or the <title> if it has no heading. On an error the paragraphs before it are returned too.
+func XHTMLText(data []byte) (string, []string, error) {
+	d := xml.NewDecoder(strings.NewReader(string(data)))
+	// NOTE: EPUB says XHTML but plenty of books have HTML entities and <br>s in them,
+	// this is how encoding/xml reads HTML. Tags that aren't closed are still an error.
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var paragraphs []string
+	var buf, heading, title strings.Builder
+	in_head, in_title, in_heading, skip := false, false, false, 0
+	flush := func() {
+		if p := strings.Join(strings.Fields(buf.String()), " "); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+		buf.Reset()
+	}
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			flush()
+			return "", paragraphs, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case XHTML_SKIP[name]:
+				skip += 1
+			case name == "head":
+				in_head = true
+			case name == "body":
+				in_head = false
+			case name == "title":
+				in_title = true
+			case XHTML_BLOCKS[name]:
+				flush()
+			}
+			if len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6' && heading.Len() == 0 {
+				in_heading = true
+			}
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case XHTML_SKIP[name]:
+				if skip > 0 {
+					skip -= 1
+				}
+			case name == "head":
+				in_head = false
+			case name == "title":
+				in_title = false
+			case XHTML_BLOCKS[name]:
+				flush()
+			}
+			if len(name) == 2 && name[0] == 'h' {
+				in_heading = false
+			}
+		case xml.CharData:
+			switch {
+			case skip > 0:
+			case in_title:
+				title.Write(t)
+			case !in_head:
+				buf.Write(t)
+				if in_heading {
+					heading.Write(t)
+				}
+			}
+		}
+	}
+	flush()
+	if h := strings.Join(strings.Fields(heading.String()), " "); h != "" {
+		return h, paragraphs, nil
+	}
+	return strings.Join(strings.Fields(title.String()), " "), paragraphs, nil
+}
+
+// LoadEPUB reads the chapters of the book at path in spine order.
+// Chapters without any text (covers, images) are left out.
+func LoadEPUB(path string) (Book, error) {
+	var book Book
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return book, fmt.Errorf("Failed to open '%s': '%v'", path, err)
+	}
+	defer zr.Close()
+	return read_epub(&zr.Reader)
+}
+
+func read_epub(zr *zip.Reader) (Book, error) {
+	var book Book
+	data, err := read_zip_file(zr, "META-INF/container.xml")
+	if err != nil {
+		return book, err
+	}
+	var container epub_container
+	if err = xml.Unmarshal(data, &container); err != nil {
+		return book, fmt.Errorf("Failed to read container.xml: '%v'", err)
+	}
+	if len(container.Rootfiles) == 0 {
+		return book, fmt.Errorf("container.xml has no rootfile")
+	}
+	opf := container.Rootfiles[0].FullPath
+	if data, err = read_zip_file(zr, opf); err != nil {
+		return book, err
+	}
+	var pkg epub_package
+	if err = xml.Unmarshal(data, &pkg); err != nil {
+		return book, fmt.Errorf("Failed to read '%s': '%v'", opf, err)
+	}
+	if len(pkg.Titles) > 0 {
+		book.Title = strings.TrimSpace(pkg.Titles[0])
+	}
+
+	hrefs := make(map[string]string)
+	for _, item := range pkg.Items {
+		if strings.Contains(item.MediaType, "html") {
+			hrefs[item.ID] = item.Href
+		}
+	}
+	var lines []string
+	for _, ref := range pkg.Spine {
+		href, ok := hrefs[ref.IDRef]
+		if !ok {
+			continue
+		}
+		// NOTE: hrefs are relative to the OPF file and URL encoded
+		if unescaped, err := url.PathUnescape(href); err == nil {
+			href = unescaped
+		}
+		name := path.Join(path.Dir(opf), href)
+		data, err := read_zip_file(zr, name)
+		if err != nil {
+			return book, err
+		}
+		title, paragraphs, err := XHTMLText(data)
+		if err != nil {
+			// NOTE: the decoder can't go on after a syntax error, we keep what it read up to there
+			fmt.Printf("[WARNING] '%s' is cut short: %v\n", name, err)
+		}
+		if len(paragraphs) == 0 {
+			continue
+		}
+		if title == "" {
+			title = fmt.Sprintf("Chapter %d", len(book.Chapters)+1)
+		}
+		book.Chapters = append(book.Chapters, Chapter{Title: title, Line: len(lines)})
+		lines = append(lines, paragraphs...)
+	}
+	book.Text = strings.Join(lines, "\n")
+	return book, nil
+}
+
+// ChapterAt returns the chapter that line is in, -1 if it's before the first one.
+func ChapterAt(chapters []Chapter, line int) int {
+	result := -1
+	for i := range chapters {
+		if chapters[i].Line > line {
+			break
+		}
+		result = i
+	}
+	return result
+}
+
+func is_epub(filename string) bool {
+	return strings.EqualFold(path.Ext(filename), ".epub")
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"strings"
+	"testing"
+)
+
+// write_epub zips files into an EPUB, the names are relative to the root of the zip.
+func write_epub(t *testing.T, files map[string]string) string {
+	path := t.TempDir() + "/book.epub"
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(data))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+var TEST_EPUB = map[string]string{
+	"mimetype": "application/epub+zip",
+	"META-INF/container.xml": `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`,
+	"OEBPS/content.opf": `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="2.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Le Petit Livre</dc:title></metadata>
+  <manifest>
+    <item id="one" href="Text/ch%201.xhtml" media-type="application/xhtml+xml"/>
+    <item id="two" href="Text/ch2.xhtml" media-type="application/xhtml+xml"/>
+    <item id="cover" href="cover.xhtml" media-type="application/xhtml+xml"/>
+    <item id="css" href="style.css" media-type="text/css"/>
+  </manifest>
+  <spine><itemref idref="cover"/><itemref idref="two"/><itemref idref="one"/><itemref idref="css"/></spine>
+</package>`,
+	"OEBPS/cover.xhtml": `<html><body><img src="cover.jpg"/></body></html>`,
+	"OEBPS/Text/ch2.xhtml": `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>Deux</title><style>p { margin: 0 }</style></head>
+<body>
+  <h1>Chapitre <em>premier</em></h1>
+  <p>Il était   une
+  fois,&nbsp;dit-il.</p>
+  <p>Ligne un<br/>ligne deux</p>
+</body></html>`,
+	"OEBPS/Text/ch 1.xhtml": `<html><head><title>La fin</title></head>
+<body><div><p>C&apos;est fini &amp; bien fini.</p><script>var x = 1;</script></div></body></html>`,
+}
+
+func TestLoadEPUB(t *testing.T) {
+	book, err := LoadEPUB(write_epub(t, TEST_EPUB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.Title != "Le Petit Livre" {
+		t.Errorf("LoadEPUB title failed: got (%q) expected (%q)", book.Title, "Le Petit Livre")
+	}
+	expected := []string{
+		"Chapitre premier",
+		"Il était une fois, dit-il.",
+		"Ligne un",
+		"ligne deux",
+		"C'est fini & bien fini.",
+	}
+	if got := strings.Split(book.Text, "\n"); strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("LoadEPUB text failed: got (%q) expected (%q)", got, expected)
+	}
+	chapters := []Chapter{{Title: "Chapitre premier", Line: 0}, {Title: "La fin", Line: 4}}
+	if len(book.Chapters) != len(chapters) {
+		t.Fatalf("LoadEPUB chapters failed: got (%v) expected (%v)", book.Chapters, chapters)
+	}
+	for i := range chapters {
+		if book.Chapters[i] != chapters[i] {
+			t.Errorf("LoadEPUB chapter %d failed: got (%v) expected (%v)", i, book.Chapters[i], chapters[i])
+		}
+	}
+
+	if _, err := LoadEPUB(write_epub(t, map[string]string{"mimetype": "application/epub+zip"})); err == nil {
+		t.Errorf("LoadEPUB without a container.xml should fail")
+	}
+}
+
+func TestXHTMLText(t *testing.T) {
+	// not XHTML, but books like this exist
+	title, paragraphs, err := XHTMLText([]byte("<p>one</p><p>two &eacute;t&eacute;<br>three</p>"))
+	expected := []string{"one", "two été", "three"}
+	if err != nil || title != "" || strings.Join(paragraphs, "|") != strings.Join(expected, "|") {
+		t.Errorf("XHTMLText failed: got (%q, %q, %v) expected (%q)", title, paragraphs, err, expected)
+	}
+	// what comes before a mistake it can't get past is kept
+	_, paragraphs, err = XHTMLText([]byte("<body><p>one</p><p>two</ul></body>"))
+	if err == nil || strings.Join(paragraphs, "|") != "one|two" {
+		t.Errorf("XHTMLText failed: got (%q, %v) expected (%q) and an error", paragraphs, err, "one|two")
+	}
+}
+
+func TestChapterAt(t *testing.T) {
+	chapters := []Chapter{{Line: 2}, {Line: 10}, {Line: 30}}
+	tests := map[int]int{0: -1, 2: 0, 9: 0, 10: 1, 29: 1, 30: 2, 1000: 2}
+	for line, expected := range tests {
+		if got := ChapterAt(chapters, line); got != expected {
+			t.Errorf("ChapterAt(%d) failed: got (%d) expected (%d)", line, got, expected)
+		}
+	}
+}
+
+func TestOpenDocumentEPUB(t *testing.T) {
+	doc, err := OpenDocument(write_epub(t, TEST_EPUB), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if len(doc.chapters) != 2 || doc.LineCount() != 5 {
+		t.Errorf("OpenDocument failed: got (%d) chapters (%d) lines", len(doc.chapters), doc.LineCount())
+	}
+	tw := NewTextWindow(doc, 200, 10)
+	if i, moved := tw.Seek(doc.chapters[1].Line); moved || tw.lines[i] != "C'est fini & bien " {
+		t.Errorf("Seek failed: got (%q, %v) expected (%q)", tw.lines[i], moved, "C'est fini & bien ")
+	}
+}
@@ -5,4 +5,9 @@ import "errors"
 var (
 	FooError = errors.New("foor error!")
 	BarError = errors.New("bar error!")
+
+	WordNotFoundError  = errors.New("word not found")
+	LegacyRecordError  = errors.New("legacy record, run DBMigrate")
+	EmptyLanguageError = errors.New("empty language")
+	NoDatabaseError    = errors.New("vocabulary db is not open")
 )
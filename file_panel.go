@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+// The "Open File" panel: the texts and subdirectories of a directory, filtered by what's typed in the console.
+// up/down picks one, return opens it (or goes into the directory), escape leaves.
+
+// the files we know how to open
+var TEXT_EXTENSIONS = []string{".txt", ".epub", ".srt", ".vtt", ".md"}
+
+// NOTE: the MultiLine texture is 300px high, this leaves room for the header and the help line
+const FILE_PANEL_ROWS = 10
+
+type FileEntry struct {
+	Name  string
+	IsDir bool
+}
+
+// ListDir returns the subdirectories of dir, then the files with one of exts, both sorted.
+// Hidden files are left out and ".." is always first, so there's a way back up.
+func ListDir(dir string, exts []string) ([]FileEntry, error) {
+	list, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var dirs, files []FileEntry
+	for _, entry := range list {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		if entry.IsDir() {
+			dirs = append(dirs, FileEntry{Name: name, IsDir: true})
+			continue
+		}
+		for _, ext := range exts {
+			if strings.EqualFold(filepath.Ext(name), ext) {
+				files = append(files, FileEntry{Name: name})
+				break
+			}
+		}
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Name < dirs[j].Name })
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return append(append([]FileEntry{{Name: "..", IsDir: true}}, dirs...), files...), nil
+}
+
+// FilterEntries keeps the entries that have filter in their name, ignoring case.
+func FilterEntries(entries []FileEntry, filter string) []FileEntry {
+	filter = strings.ToLower(strings.TrimSpace(filter))
+	if filter == "" {
+		return entries
+	}
+	var result []FileEntry
+	for _, entry := range entries {
+		if entry.Name != ".." && strings.Contains(strings.ToLower(entry.Name), filter) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+type FilePanel struct {
+	show     bool
+	font     *ttf.Font
+	font_w   int
+	text     MultiLine
+	dir      string
+	entries  []FileEntry
+	filter   string
+	filtered []FileEntry
+	selected int
+	err      error // why dir couldn't be listed
+}
+
+func NewFilePanel(renderer *sdl.Renderer, font *ttf.Font) FilePanel {
+	fp := FilePanel{font: font}
+	fp.font_w, _, _ = font.SizeUTF8(" ")
+	fp.text.New(renderer, font)
+	return fp
+}
+
+func (fp *FilePanel) Open(renderer *sdl.Renderer, dir string) {
+	fp.dir = filepath.Clean(dir)
+	fp.entries, fp.err = ListDir(fp.dir, TEXT_EXTENSIONS)
+	fp.filter = ""
+	fp.filtered = fp.entries
+	fp.selected = 0
+	fp.show = true
+	fp.Redraw(renderer)
+}
+
+func (fp *FilePanel) Close() {
+	fp.show = false
+	fp.entries = nil
+	fp.filtered = nil
+}
+
+func (fp *FilePanel) SetFilter(renderer *sdl.Renderer, filter string) {
+	if filter == fp.filter {
+		return
+	}
+	fp.filter = filter
+	fp.filtered = FilterEntries(fp.entries, filter)
+	fp.selected = 0
+	fp.Redraw(renderer)
+}
+
+// HandleKey returns the path of the file that was picked with return.
+// Picking a directory opens it in the panel, the caller should clear the filter then.
+func (fp *FilePanel) HandleKey(renderer *sdl.Renderer, key sdl.Keycode) (string, bool) {
+	switch key {
+	case sdl.K_ESCAPE:
+		fp.Close()
+	case sdl.K_UP, sdl.K_DOWN:
+		if n := len(fp.filtered); n > 0 {
+			delta := 1
+			if key == sdl.K_UP {
+				delta = -1
+			}
+			fp.selected = ((fp.selected+delta)%n + n) % n
+			fp.Redraw(renderer)
+		}
+	case sdl.K_RETURN:
+		if fp.selected >= len(fp.filtered) {
+			break
+		}
+		entry := fp.filtered[fp.selected]
+		path := filepath.Join(fp.dir, entry.Name)
+		if entry.IsDir {
+			fp.Open(renderer, path)
+			break
+		}
+		fp.Close()
+		return path, true
+	}
+	return "", false
+}
+
+func (fp *FilePanel) panel_lines() []string {
+	lines := []string{fp.dir + string(filepath.Separator), "filter: " + fp.filter, " "}
+	if fp.err != nil {
+		return append(lines, fp.err.Error(), " ", "[esc] back")
+	}
+	if len(fp.filtered) == 0 {
+		lines = append(lines, "Nothing matches.")
+	}
+	// a window of FILE_PANEL_ROWS entries that follows the selection
+	first := 0
+	if fp.selected >= FILE_PANEL_ROWS {
+		first = fp.selected - FILE_PANEL_ROWS + 1
+	}
+	for i := first; i < len(fp.filtered) && i < first+FILE_PANEL_ROWS; i++ {
+		marker := "  "
+		if i == fp.selected {
+			marker = "> "
+		}
+		name := fp.filtered[i].Name
+		if fp.filtered[i].IsDir {
+			name += string(filepath.Separator)
+		}
+		lines = append(lines, marker+name)
+	}
+	return append(lines, " ", fmt.Sprintf("%d/%d [return] open [esc] back", min_int(fp.selected+1, len(fp.filtered)), len(fp.filtered)))
+}
+
+func (fp *FilePanel) Redraw(renderer *sdl.Renderer) {
+	var lines []string
+	for _, line := range fp.panel_lines() {
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, " ") // NOTE: RenderUTF8Blended fails on ""
+			continue
+		}
+		lines = append(lines, NewWrapLines(line, int(fp.text.bg_rect.W), fp.font_w)...)
+	}
+	fp.text.ClearAndWrite(renderer, fp.font, lines, fp.text.lineskip, 0)
+}
+
+func (fp *FilePanel) Draw(renderer *sdl.Renderer) {
+	w, h, _ := renderer.GetOutputSize()
+	pad := int32(10)
+	fp.text.bg_rect.X = (w - fp.text.bg_rect.W) / 2
+	fp.text.bg_rect.Y = (h - fp.text.bg_rect.H) / 2
+	bg := sdl.Rect{X: fp.text.bg_rect.X - pad, Y: fp.text.bg_rect.Y - pad, W: fp.text.bg_rect.W + pad*2, H: fp.text.bg_rect.H + pad*2}
+	draw_rect_with_border_filled(renderer, &bg, &COLOR_IRON)
+	renderer.Copy(fp.text.texture, nil, &fp.text.bg_rect)
+}
+
+func (fp *FilePanel) Destroy() {
+	fp.text.texture.Destroy()
+	fp.text.fmt.Free()
+	fp.font.Close()
+}
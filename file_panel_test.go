@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestListDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"French.txt", "Hanyu.TXT", "notes.doc", ".hidden.txt", "French.txt.cfg"} {
+		os.WriteFile(filepath.Join(dir, name), []byte("text"), FILE_MODE_RW)
+	}
+	os.Mkdir(filepath.Join(dir, "zh"), 0700)
+	os.Mkdir(filepath.Join(dir, "books"), 0700)
+	os.Mkdir(filepath.Join(dir, ".git"), 0700)
+
+	entries, err := ListDir(dir, TEXT_EXTENSIONS)
+	if err != nil {
+		t.Fatalf("ListDir failed: %v", err)
+	}
+	expected := []FileEntry{{"..", true}, {"books", true}, {"zh", true}, {"French.txt", false}, {"Hanyu.TXT", false}}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("ListDir failed: got (%v) expected (%v)", entries, expected)
+	}
+	if _, err := ListDir(filepath.Join(dir, "missing"), TEXT_EXTENSIONS); err == nil {
+		t.Errorf("ListDir on a missing dir should fail")
+	}
+
+	test := []struct {
+		filter   string
+		expected []FileEntry
+	}{
+		{filter: "", expected: entries},
+		{filter: " ", expected: entries},
+		{filter: "fren", expected: []FileEntry{{"French.txt", false}}},
+		{filter: "H", expected: []FileEntry{{"zh", true}, {"French.txt", false}, {"Hanyu.TXT", false}}},
+		{filter: "o", expected: []FileEntry{{"books", true}}},
+		{filter: "..", expected: nil},
+	}
+	for _, tt := range test {
+		if result := FilterEntries(entries, tt.filter); !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("FilterEntries(%q) failed: got (%v) expected (%v)", tt.filter, result, tt.expected)
+		}
+	}
+}
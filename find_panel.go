@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+// The "find <word>" results: vocabulary words that are a few typos away from what was typed.
+// up/down picks one, return jumps to where it's first used in the text, escape leaves.
+
+// NOTE: the MultiLine texture is 300px high, more than this doesn't fit under the header
+const FIND_RESULTS = 8
+
+type FindPanel struct {
+	show     bool
+	font     *ttf.Font
+	font_w   int
+	text     MultiLine
+	query    string
+	matches  []FuzzyMatch
+	lines    []int // the first line of every match in the text, -1 if it's not in there
+	selected int
+}
+
+func NewFindPanel(renderer *sdl.Renderer, font *ttf.Font) FindPanel {
+	fp := FindPanel{font: font}
+	fp.font_w, _, _ = font.SizeUTF8(" ")
+	fp.text.New(renderer, font)
+	return fp
+}
+
+// FindMaxDistance is how many typos we forgive: one in a short word, two in a longer one.
+func FindMaxDistance(word string) int {
+	if utf8.RuneCountInString(word) <= 4 {
+		return 1
+	}
+	return 2
+}
+
+// WordCounts is how many times every word key is in the text.
+func WordCounts(linemeta []LineMetaData) map[string]int {
+	counts := make(map[string]int)
+	for i := range linemeta {
+		for _, key := range linemeta[i].word_keys {
+			if key != "" {
+				counts[key] += 1
+			}
+		}
+	}
+	return counts
+}
+
+// FirstLine returns the first line of the text that has key in it, or -1.
+func FirstLine(linemeta []LineMetaData, key string) int {
+	for i := range linemeta {
+		for _, k := range linemeta[i].word_keys {
+			if k == key {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func (fp *FindPanel) Open(renderer *sdl.Renderer, query string, matches []FuzzyMatch, linemeta []LineMetaData) {
+	if len(matches) > FIND_RESULTS {
+		matches = matches[:FIND_RESULTS]
+	}
+	fp.query = query
+	fp.matches = matches
+	fp.lines = make([]int, len(matches))
+	for i := range matches {
+		fp.lines[i] = FirstLine(linemeta, matches[i].Word)
+	}
+	fp.selected = 0
+	fp.show = true
+	fp.Redraw(renderer)
+}
+
+func (fp *FindPanel) Close() {
+	fp.show = false
+	fp.matches = nil
+	fp.lines = nil
+}
+
+// HandleKey returns the line to jump to when a match is picked with return.
+func (fp *FindPanel) HandleKey(renderer *sdl.Renderer, key sdl.Keycode) (int, bool) {
+	switch key {
+	case sdl.K_ESCAPE:
+		fp.Close()
+	case sdl.K_UP, sdl.K_DOWN:
+		if n := len(fp.matches); n > 0 {
+			delta := 1
+			if key == sdl.K_UP {
+				delta = -1
+			}
+			fp.selected = ((fp.selected+delta)%n + n) % n
+			fp.Redraw(renderer)
+		}
+	case sdl.K_RETURN:
+		if fp.selected < len(fp.lines) && fp.lines[fp.selected] >= 0 {
+			line := fp.lines[fp.selected]
+			fp.Close()
+			return line, true
+		}
+	}
+	return -1, false
+}
+
+func (fp *FindPanel) panel_lines() []string {
+	lines := []string{fmt.Sprintf("find '%s'", fp.query), " "}
+	if len(fp.matches) == 0 {
+		lines = append(lines, "Nothing close enough.")
+	}
+	for i, m := range fp.matches {
+		marker := "  "
+		if i == fp.selected {
+			marker = "> "
+		}
+		where := "not in this text"
+		if fp.lines[i] >= 0 {
+			where = fmt.Sprintf("line %d, %dx", fp.lines[i]+1, m.Count)
+		}
+		lines = append(lines, fmt.Sprintf("%s%s (%d) %s", marker, m.Word, m.Distance, where))
+	}
+	return append(lines, " ", "[return] go to it [esc] back")
+}
+
+func (fp *FindPanel) Redraw(renderer *sdl.Renderer) {
+	var lines []string
+	for _, line := range fp.panel_lines() {
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, " ") // NOTE: RenderUTF8Blended fails on ""
+			continue
+		}
+		lines = append(lines, NewWrapLines(line, int(fp.text.bg_rect.W), fp.font_w)...)
+	}
+	fp.text.ClearAndWrite(renderer, fp.font, lines, fp.text.lineskip, 0)
+}
+
+func (fp *FindPanel) Draw(renderer *sdl.Renderer) {
+	w, h, _ := renderer.GetOutputSize()
+	pad := int32(10)
+	fp.text.bg_rect.X = (w - fp.text.bg_rect.W) / 2
+	fp.text.bg_rect.Y = (h - fp.text.bg_rect.H) / 2
+	bg := sdl.Rect{X: fp.text.bg_rect.X - pad, Y: fp.text.bg_rect.Y - pad, W: fp.text.bg_rect.W + pad*2, H: fp.text.bg_rect.H + pad*2}
+	draw_rect_with_border_filled(renderer, &bg, &COLOR_IRON)
+	renderer.Copy(fp.text.texture, nil, &fp.text.bg_rect)
+}
+
+func (fp *FindPanel) Destroy() {
+	fp.text.texture.Destroy()
+	fp.text.fmt.Free()
+	fp.font.Close()
+}
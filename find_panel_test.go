@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestWordCountsFirstLine(t *testing.T) {
+	linemeta := []LineMetaData{
+		{word_keys: []string{"in", "a", "hole", ""}},
+		{word_keys: []string{"a", "hobbit"}},
+		{},
+		{word_keys: []string{"hobbit"}},
+	}
+	counts := WordCounts(linemeta)
+	if counts["hobbit"] != 2 || counts["a"] != 2 || counts["hole"] != 1 || len(counts) != 4 {
+		t.Errorf("WordCounts failed: got (%v)", counts)
+	}
+	for word, line := range map[string]int{"in": 0, "hobbit": 1, "bilbo": -1} {
+		if got := FirstLine(linemeta, word); got != line {
+			t.Errorf("FirstLine(%s) failed: got (%d) expected (%d)", word, got, line)
+		}
+	}
+}
@@ -1,34 +1,175 @@
 package main
 
 import (
+	"fmt"
 	"strings"
+	"unicode/utf8"
+
+	"tester/tokenizer"
 )
 
-// we need to make sure we can add:
-// the possibility to create predefined rules on the fly in real time
+// WORD_SEGMENTER cuts Chinese and Japanese into words, LoadSegmenter swaps in a dictionary.
+var WORD_SEGMENTER tokenizer.Segmenter = tokenizer.RuneSegmenter{}
+
+func LoadSegmenter(config AppConfig) error {
+	if config.cedict == "" {
+		return nil
+	}
+	mm, err := tokenizer.LoadCEDICTFile(config.cedict)
+	if err != nil {
+		return fmt.Errorf("Failed to load cedict: '%v'", err)
+	}
+	WORD_SEGMENTER = mm
+	return nil
+}
+
+// TEXT_RULES are the tokenizer rules of the language we are reading, UseLanguage sets them.
+var TEXT_RULES = tokenizer.DEFAULT_RULES
+
+// LineWords is every word in line, with the CJK parts segmented.
+func LineWords(line string) []tokenizer.Token {
+	return tokenizer.Segment(TEXT_RULES.Words(line), WORD_SEGMENTER)
+}
+
+// NOTE: the rules for finding words come from TEXT_RULES, and the "rules reload" console command
+// re-reads them while the app is running
 func GetUniqueWords(s []string) map[string]struct{} {
 	mk := make(map[string]struct{})
 	for i := 0; i < len(s); i++ {
-		words := strings.Split(s[i], " ")
-		for _, w := range words {
-			if HasNonAlpha(w) {
-				trim := strings.ToLower(strings.Trim(w, ",.\n\r\\/\"'-;%^$#*@(!?)_-+=:<>[]{}~|"))
-				if !AllNonAlpha(w) {
-					if _, ok := mk[trim]; !ok {
-						mk[trim] = struct{}{}
+		for _, tok := range LineWords(s[i]) {
+			mk[WordKey(tok.Text)] = struct{}{}
+		}
+	}
+	return mk
+}
+
+type WordContext struct {
+	Form     string `json:"form,omitempty"` // the word as it was written, if that isn't the key
+	File     string `json:"file"`
+	Offset   int    `json:"offset"` // byte offset of the word in File
+	Sentence string `json:"sentence"`
+}
+
+// GetUniqueWordsWithContext finds the same words as GetUniqueWords,
+// and also remembers where each of them was seen for the first time.
+func GetUniqueWordsWithContext(filename string, text string) map[string]WordContext {
+	mk := make(map[string]WordContext)
+	line_start := 0
+	for _, line := range strings.Split(text, "\n") {
+		for _, sentence := range SplitSentences(line) {
+			for _, tok := range LineWords(sentence.text) {
+				key := WordKey(tok.Text)
+				if _, ok := mk[key]; !ok {
+					mk[key] = WordContext{
+						Form:     surface_form(key, tok.Text),
+						File:     filename,
+						Offset:   line_start + sentence.offset + tok.Offset,
+						Sentence: strings.TrimSpace(sentence.text),
 					}
 				}
-			} else {
-				if w != "" {
-					mk[w] = struct{}{}
-				}
 			}
 		}
-		words = nil
+		line_start += len(line) + 1 // +1 is the "\n"
 	}
 	return mk
 }
 
+func surface_form(key string, word string) string {
+	if FoldWord(word) == key {
+		return ""
+	}
+	return word
+}
+
+type sentence_span struct {
+	text   string
+	offset int // in the line
+}
+
+// SplitSentences cuts a line after '.', '!', '?' and '…' (and any closing quotes/brackets after them)
+// when they are followed by a space, but not after the abbreviations in TEXT_RULES. Every line ends a sentence too.
+func SplitSentences(line string) []sentence_span {
+	var result []sentence_span
+	start := 0
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		i += size
+		if !strings.ContainsRune(".!?…", r) {
+			continue
+		}
+		for i < len(line) {
+			r, size = utf8.DecodeRuneInString(line[i:])
+			if !strings.ContainsRune(".!?…\"'»”’)]", r) {
+				break
+			}
+			i += size
+		}
+		if i < len(line) && line[i] != ' ' {
+			continue // "3.14", "a.k.a", "l'homme"
+		}
+		if line[i-1] == '.' && TEXT_RULES.IsAbbreviation(line[strings.LastIndex(line[:i], " ")+1:i]) {
+			continue // "M. Dupont", "etc. and so on"
+		}
+		if strings.TrimSpace(line[start:i]) != "" {
+			result = append(result, sentence_span{line[start:i], start})
+		}
+		start = i
+	}
+	if strings.TrimSpace(line[start:]) != "" {
+		result = append(result, sentence_span{line[start:], start})
+	}
+	return result
+}
+
+// SplitLineWords splits a line on " " (that's what gets one rect each in LineMetaData)
+// and cuts the chunks with CJK in them further, at the words WORD_SEGMENTER finds.
+// glued[i] is true when text[i+1] follows text[i] without a space.
+func SplitLineWords(line string) (text []string, glued []bool) {
+	for _, chunk := range strings.Split(line, " ") {
+		if strings.IndexFunc(chunk, tokenizer.IsCJK) == -1 {
+			text = append(text, chunk)
+			glued = append(glued, false)
+			continue
+		}
+		start := 0
+		for i, tok := range LineWords(chunk) {
+			if i == 0 {
+				continue // NOTE: punctuation stays with the word next to it, "「我们" is one piece
+			}
+			text = append(text, chunk[start:tok.Offset])
+			glued = append(glued, true)
+			start = tok.Offset
+		}
+		text = append(text, chunk[start:])
+		glued = append(glued, false)
+	}
+	return text, glued
+}
+
+// FoldWord is unicode case folding, "Maison" and "MAISON" are "maison", "Straße" is "strasse".
+func FoldWord(word string) string {
+	return tokenizer.Fold(word) // NOTE: the ignore list of the rules is folded the same way
+}
+
+// WordKey turns a tokenizer.WORD into the key we store in the db: folded, and the lemma if WORD_LEMMATIZER knows it.
+func WordKey(word string) string {
+	key := FoldWord(word)
+	if WORD_LEMMATIZER != nil {
+		return WORD_LEMMATIZER.Lemma(key)
+	}
+	return key
+}
+
+// NormalizeWord is WordKey for a space separated chunk of a line, like "\"Bilbo!\"".
+// It returns "" for chunks without a word in them (like "\n" or "...").
+func NormalizeWord(w string) string {
+	words := LineWords(w)
+	if len(words) == 0 {
+		return ""
+	}
+	return WordKey(words[0].Text)
+}
+
 // This function should be used with font.GlyphIsProvided
 // that way we can query font for supported characters that we need
 func GetUniqueChars(s string) map[string]struct{} {
@@ -42,24 +183,6 @@ func GetUniqueChars(s string) map[string]struct{} {
 	return mk
 }
 
-func HasNonAlpha(str string) bool {
-	for _, c := range []byte(str) {
-		if !IsAlpha(c) {
-			return true
-		}
-	}
-	return false
-}
-
-func AllNonAlpha(str string) bool {
-	for _, c := range []byte(str) {
-		if IsAlpha(c) {
-			return false
-		}
-	}
-	return true
-}
-
 func HasCapitalLetter(str string) bool {
 	for _, c := range []byte(str) {
 		if IsCapital(c) {
@@ -73,6 +196,9 @@ func IsCapital(c byte) bool {
 	return (c >= byte('A')) && (c <= byte('Z'))
 }
 
-func IsAlpha(c byte) bool {
-	return (c >= byte('A')) && (c <= byte('z'))
+func min_int(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
 }
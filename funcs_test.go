@@ -1,8 +1,11 @@
 package main
 
 import (
+	"reflect"
 	"strings"
 	"testing"
+
+	"tester/tokenizer"
 )
 
 //TODO: write tests where retrieve values from map[string]struct{}
@@ -17,7 +20,7 @@ var tests = []struct {
 	{input: "one", output: []string{"one"}},
 	{input: "one..", output: []string{"one"}},
 	{input: "free'd", output: []string{"free'd"}},
-	{input: "l'amour", output: []string{"l'amour"}},
+	{input: "l'amour", output: []string{"amour"}},
 	{input: "forget-me-not", output: []string{"forget-me-not"}},
 	{input: "one!!!---", output: []string{"one"}},
 	{input: "..one..", output: []string{"one"}},
@@ -59,3 +62,81 @@ func BenchmarkGetUniqueWords(b *testing.B) {
 	}
 	_ = words
 }
+
+func TestNormalizeWord(t *testing.T) {
+	test := []struct {
+		input    string
+		expected string
+	}{
+		{input: "Hobbit,", expected: "hobbit"},
+		{input: "hobbit", expected: "hobbit"},
+		{input: "\"Bilbo!\"", expected: "bilbo"},
+		{input: "...", expected: ""},
+		{input: "\n", expected: ""},
+	}
+	for _, tt := range test {
+		if result := NormalizeWord(tt.input); result != tt.expected {
+			t.Errorf("TestNormalizeWord failed: got (%s) expected (%s)", result, tt.expected)
+		}
+	}
+}
+
+func TestGetUniqueWordsWithContext(t *testing.T) {
+	text := "In a hole in the ground there lived a hobbit. Not a nasty, dirty, wet hole!\n\"Good Morning!\" said Bilbo."
+	words := GetUniqueWordsWithContext("HP01.txt", text)
+
+	test := []struct {
+		word     string
+		offset   int
+		sentence string
+	}{
+		{word: "in", offset: 0, sentence: "In a hole in the ground there lived a hobbit."},
+		{word: "hobbit", offset: 38, sentence: "In a hole in the ground there lived a hobbit."},
+		{word: "nasty", offset: 52, sentence: "Not a nasty, dirty, wet hole!"},
+		{word: "hole", offset: 5, sentence: "In a hole in the ground there lived a hobbit."},
+		{word: "morning", offset: 82, sentence: "\"Good Morning!\""},
+		{word: "bilbo", offset: 97, sentence: "said Bilbo."},
+	}
+	for _, tt := range test {
+		ctx, ok := words[tt.word]
+		if !ok {
+			t.Errorf("TestGetUniqueWordsWithContext failed: (%s) not found", tt.word)
+			continue
+		}
+		if ctx.File != "HP01.txt" || ctx.Offset != tt.offset || ctx.Sentence != tt.sentence {
+			t.Errorf("TestGetUniqueWordsWithContext failed: (%s) got (%+v) expected (%d, %s)", tt.word, ctx, tt.offset, tt.sentence)
+		}
+	}
+	if len(words) != len(GetUniqueWords(strings.Split(text, "\n"))) {
+		t.Errorf("TestGetUniqueWordsWithContext failed: found different words than GetUniqueWords")
+	}
+}
+
+func TestSplitLineWords(t *testing.T) {
+	defer func(seg tokenizer.Segmenter) { WORD_SEGMENTER = seg }(WORD_SEGMENTER)
+	WORD_SEGMENTER = tokenizer.NewMaxMatch([]string{"我们", "喜欢", "中国"})
+
+	test := []struct {
+		input string
+		text  []string
+		glued []bool
+	}{
+		{input: "one two.", text: []string{"one", "two."}, glued: []bool{false, false}},
+		{input: "「我们喜欢中国。」 ok", text: []string{"「我们", "喜欢", "中国。」", "ok"}, glued: []bool{true, true, false, false}},
+		{input: "我们，喜欢", text: []string{"我们，", "喜欢"}, glued: []bool{true, false}},
+		{input: "好 ", text: []string{"好", ""}, glued: []bool{false, false}},
+	}
+	for _, tt := range test {
+		text, glued := SplitLineWords(tt.input)
+		if !reflect.DeepEqual(text, tt.text) || !reflect.DeepEqual(glued, tt.glued) {
+			t.Errorf("TestSplitLineWords(%s) failed: got (%q, %v) expected (%q, %v)", tt.input, text, glued, tt.text, tt.glued)
+		}
+	}
+
+	words := GetUniqueWords([]string{"我们喜欢中国。"})
+	for _, key := range []string{"我们", "喜欢", "中国"} {
+		if _, ok := words[key]; !ok || len(words) != 3 {
+			t.Errorf("GetUniqueWords failed on CJK: got (%v) expected (%s) in it", words, key)
+		}
+	}
+}
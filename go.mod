@@ -5,11 +5,11 @@ go 1.19
 require (
 	github.com/veandco/go-sdl2 v0.4.27
 	go.etcd.io/bbolt v1.3.6
+	golang.org/x/text v0.4.0
 )
 
 require (
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
 	golang.org/x/image v0.1.0 // indirect
 	golang.org/x/sys v0.0.0-20220722155257-8c9f86f7a55f // indirect
-	golang.org/x/text v0.4.0 // indirect
 )
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// A Lemmatizer maps an inflected form to the word we keep in the vocabulary, "maisons" to "maison".
+// The text itself keeps the form it was written in, only the db key changes.
+type Lemmatizer interface {
+	// Lemma gets a FoldWord'ed form and returns it as it is if it doesn't know it.
+	Lemma(form string) string
+}
+
+type LemmaTable map[string]string // form -> lemma
+
+func (lt LemmaTable) Lemma(form string) string {
+	if lemma, ok := lt[form]; ok {
+		return lemma
+	}
+	return form
+}
+
+// LoadLemmaFile reads "lemma<TAB>form" lines, the format of the usual lemmatization lists:
+//
+//	maison	maisons
+//	être	suis
+func LoadLemmaFile(path string) (LemmaTable, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	result := make(LemmaTable)
+	scanner := bufio.NewScanner(file)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected 'lemma form', got '%s'", path, lineno, line)
+		}
+		lemma, form := FoldWord(fields[0]), FoldWord(fields[1])
+		if form != lemma {
+			result[form] = lemma
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// WORD_LEMMATIZER is the one for the language we are reading, nil keeps every form as its own word.
+var WORD_LEMMATIZER Lemmatizer
+
+var lemma_tables = make(map[string]LemmaTable) // by path, so switching languages back and forth is cheap
+
+// use_lemmas sets WORD_LEMMATIZER from the "lemmas.<lang>" setting.
+func use_lemmas(config AppConfig, lang string) error {
+	path := config.lemmas[lang]
+	if path == "" {
+		WORD_LEMMATIZER = nil
+		return nil
+	}
+	table, ok := lemma_tables[path]
+	if !ok {
+		var err error
+		if table, err = LoadLemmaFile(path); err != nil {
+			WORD_LEMMATIZER = nil
+			return fmt.Errorf("Failed to load lemmas for '%s': '%v'", lang, err)
+		}
+		lemma_tables[path] = table
+	}
+	WORD_LEMMATIZER = table
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFoldWord(t *testing.T) {
+	test := []struct {
+		input    string
+		expected string
+	}{
+		{input: "Maison", expected: "maison"},
+		{input: "MAISON", expected: "maison"},
+		{input: "Été", expected: "été"},
+		{input: "Straße", expected: "strasse"},
+		{input: "ΣΟΦΌΣ", expected: "σοφόσ"},
+		{input: "我们", expected: "我们"},
+	}
+	for _, tt := range test {
+		if result := FoldWord(tt.input); result != tt.expected {
+			t.Errorf("TestFoldWord failed: got (%s) expected (%s)", result, tt.expected)
+		}
+	}
+}
+
+func TestLemmatizer(t *testing.T) {
+	defer func(lemmatizer Lemmatizer) { WORD_LEMMATIZER = lemmatizer }(WORD_LEMMATIZER)
+
+	path := filepath.Join(t.TempDir(), "fr.txt")
+	os.WriteFile(path, []byte("# lemma form\nmaison\tmaisons\nêtre\tsuis\nÊtre\tEst\n"), FILE_MODE_RW)
+	config := AppConfig{lemmas: map[string]string{"fr": path}}
+
+	if err := UseLanguage(config, "fr"); err != nil {
+		t.Fatalf("UseLanguage failed: %v", err)
+	}
+	for form, lemma := range map[string]string{"Maisons": "maison", "maison": "maison", "suis": "être", "EST": "être", "chat": "chat"} {
+		if result := WordKey(form); result != lemma {
+			t.Errorf("WordKey(%s) failed: got (%s) expected (%s)", form, result, lemma)
+		}
+	}
+
+	words := GetUniqueWordsWithContext("fr.txt", "Les maisons. La Maison est")
+	if len(words) != 4 || words["maison"].Form != "maisons" || words["maison"].Offset != 4 || words["être"].Form != "est" {
+		t.Errorf("GetUniqueWordsWithContext failed with a lemmatizer: got (%+v)", words)
+	}
+
+	if err := UseLanguage(config, "de"); err != nil || WORD_LEMMATIZER != nil {
+		t.Errorf("UseLanguage failed: got (%v, %v) expected no lemmatizer for 'de'", WORD_LEMMATIZER, err)
+	}
+	if err := UseLanguage(AppConfig{lemmas: map[string]string{"fr": path + ".missing"}}, "fr"); err == nil {
+		t.Errorf("UseLanguage should fail on a missing lemma file")
+	}
+}
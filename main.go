@@ -10,6 +10,7 @@ import (
 	"log"
 	"math"
 	"os"
+	"path/filepath"
 	"runtime"
 	"runtime/pprof"
 	"runtime/trace"
@@ -22,6 +23,9 @@ import (
 
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/veandco/go-sdl2/ttf"
+	bolt "go.etcd.io/bbolt"
+
+	"tester/tokenizer"
 )
 
 const WIN_TITLE string = "App"
@@ -29,7 +33,11 @@ const WIN_TITLE string = "App"
 const WIN_W int32 = 800
 const WIN_H int32 = 600
 
-const X_OFFSET int = 7
+const X_OFFSET_TEXT int = 7
+
+// NOTE: the text moves right to make room for the cue times of subtitles, see TimeGutter
+var X_OFFSET int = X_OFFSET_TEXT
+
 const TTF_FONT_SIZE int = 16
 const TTF_FONT_SIZE_FOR_FONT_LIST int = 12
 const LINE_LENGTH int = 500
@@ -71,10 +79,22 @@ var (
 	COLOR_SUPERNOVA        = sdl.Color{R: 255, G: 203, B: 5, A: 255}
 )
 
+// indexed by WordStatus, A: 0 means we don't shade the word at all
+var WORD_STATUS_COLORS = [...]sdl.Color{
+	WORD_STATUS_NEW:      sdl.Color{R: 44, G: 130, B: 201, A: 70},
+	WORD_STATUS_LEARNING: sdl.Color{R: 255, G: 203, B: 5, A: 90},
+	WORD_STATUS_KNOWN:    sdl.Color{R: 0, G: 0, B: 0, A: 0},
+	WORD_STATUS_IGNORED:  sdl.Color{R: 0, G: 0, B: 0, A: 0},
+}
+
 type LineMetaData struct {
 	words           []string
+	word_keys       []string // NormalizeWord(words[i]), "" if it's not a word
+	word_status     []WordStatus
 	word_rects      []sdl.Rect
 	mouse_over_word []bool
+	cue             *Cue        // the subtitle the line is from, nil for other texts
+	runs            []StyledRun // the styled parts of the line, nil if it's all plain
 }
 
 type TextBox struct {
@@ -84,6 +104,7 @@ type TextBox struct {
 	data_rects []sdl.Rect
 	metadata   []*LineMetaData // store [START:END] instead?
 	fmt        *sdl.PixelFormat
+	styled     []*LineMetaData // the line data[i] was drawn with its styles for, see UpdateStyles
 }
 
 type DebugWrapLine struct {
@@ -203,26 +224,45 @@ const (
 	GUI_ID_POPUP
 )
 
-const (
-	BOM_NONE_OR_UNSUPPORTED uint8 = iota
-	BOM_UTF8
-	BOM_UTF16_BE
-	BOM_UTF16_LE
-	BOM_UTF32_BE
-	BOM_UTF32_LE
-)
-
 func main() {
 	// PROFILING SNIPPET
 
 	var debug bool
 	var do_trace bool
+	var flag_lang string
+	var import_testwords bool
+	var flag_db string
+	var flag_config string
 
 	flag.BoolVar(&debug, "debug", false, "debug needs a bool value: -debug=true")
 	flag.BoolVar(&do_trace, "trace", false, "trace needs a bool value: -trace=true")
+	flag.StringVar(&flag_lang, "lang", "", "vocabulary language of the text, overrides the text's .cfg: -lang=fr")
+	flag.BoolVar(&import_testwords, "import-testwords", false, "copy the old TestWords bucket into the text's language: -import-testwords=true")
+	flag.StringVar(&flag_db, "db", "", "path to the vocabulary db, overrides the config file: -db=/path/to/my.db")
+	flag.StringVar(&flag_config, "config", DefaultConfigPath(), "path to the config file: -config=./tester.cfg")
 
 	flag.Parse()
 
+	config, err := LoadAppConfig(flag_config)
+	if err != nil {
+		fmt.Println("[WARNING]", err)
+	}
+	if flag_db != "" {
+		config.db_path = flag_db
+	}
+	if err = LoadSegmenter(config); err != nil {
+		fmt.Println("[WARNING]", err)
+	}
+
+	// NOTE: subcommands like 'tester vocab export' run without a window
+	if flag.NArg() > 0 {
+		if err := RunCommand(flag.Args(), config); err != nil {
+			fmt.Fprintln(os.Stderr, "[ERROR]", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if *cpuprofile != "" {
 		f, err := os.Create(*cpuprofile)
 		if err != nil {
@@ -296,8 +336,14 @@ func main() {
 	//img_tx.SetBlendMode(sdl.BLENDMODE_BLEND)
 	//img_tx_rect := sdl.Rect{int32(LINE_LENGTH) - 100, 0, 40, 40}
 
-	db := DBOpen()
-	defer db.Close()
+	// NOTE: db is nil if we couldn't open it, we show db_err in the console once it exists
+	db, db_err := DBOpen(config.db_path, config.db_timeout)
+	if db != nil {
+		defer db.Close()
+		if err = DBMigrate(db); err != nil {
+			fmt.Println("[ERROR]", err)
+		}
+	}
 
 	cursors := []*sdl.Cursor{
 		sdl.CreateSystemCursor(sdl.SYSTEM_CURSOR_ARROW),
@@ -315,15 +361,25 @@ func main() {
 	//"HP01.txt"
 	filename := "French.txt"
 	font_dir := "./fonts/"
-	text_dir := "./text/"
+	text_dir := DEFAULT_TEXT_DIR
+	file_dir := text_dir // where filename is, it changes when another file is opened
 
-	file_data := string(get_filedata(text_dir, filename))
-	line_tokens := strings.Split(file_data, "\n") // "\r\n" instead of "\n"?
+	lang := TextLanguage(file_dir, filename, flag_lang)
+	if err = UseLanguage(config, lang); err != nil {
+		fmt.Println("[WARNING]", err)
+	}
+	println("[INFO] vocabulary language:", lang)
+
+	doc, err := OpenDocument(file_dir+filename, TextEncoding(file_dir, filename))
+	if err != nil {
+		panic(err)
+	}
+	defer func() { doc.Close() }() // NOTE: doc changes when another file is opened
 
 	ticker := time.NewTicker(time.Second / 60)
 
 	ttf_font_list := get_filenames(font_dir, []string{"ttf", "otf"})
-	txt_list := get_filenames(text_dir, []string{".txt"})
+	txt_list := get_filenames(text_dir, TEXT_EXTENSIONS)
 	fmt.Println(txt_list)
 
 	var gfonts FontSelector
@@ -337,14 +393,25 @@ func main() {
 	//FontHasGlyphsFromRangeTable(font, unicode.Latin)
 	//test_tokens := WrapLines(line_tokens, LINE_LENGTH, gfonts.current_font_w)
 
+	// the bold and italic of Markdown
+	var styles StyleFonts
+	styles.Load(font_dir, gfonts.current_name, TTF_FONT_SIZE)
+
+	// the cue times of subtitles go left of the text
+	gutter := NewTimeGutter(load_font(font_dir+"Inconsolata-Regular.ttf", 12))
+	X_OFFSET = X_OFFSET_TEXT + gutter.Width(doc.cues)
+
 	start := time.Now()
-	test_tokens := NewWrapLines(file_data, LINE_LENGTH, gfonts.current_font_w)
+	text_window := NewTextWindow(doc, LINE_LENGTH, gfonts.current_font_w)
+	test_tokens := text_window.lines
 	fmt.Println("[BENCHMARK] new_test_tokens took:", time.Now().Sub(start))
 
 	TEST_TOKENS_LEN := len(test_tokens)
 
 	linemeta := make([]LineMetaData, TEST_TOKENS_LEN)
 	generate_line_metadata(font, &linemeta, &test_tokens)
+	text_window.SetLineData(linemeta)
+	styles.MeasureWords(font, linemeta)
 
 	cmd := NewCmdConsole(renderer)
 
@@ -357,18 +424,30 @@ func main() {
 
 	renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
 
-	known_word_data := GetUniqueWords(line_tokens)
+	known_word_data := text_window.UniqueWords(filename)
 
 	// DB stuff
-	if err = DBInit(db, known_word_data); err != nil {
-		fmt.Errorf("Something went wrong %v", err)
-	}
+	if db != nil {
+		if import_testwords {
+			imported, err := DBImportTestWords(db, lang)
+			if err != nil {
+				fmt.Println("Something went wrong", err)
+			}
+			println("[INFO] imported", imported, "words from TestWords into", lang)
+		}
 
-	if err = DBInsert(db, "hobbit"); err != nil {
-		fmt.Errorf("Something went wrong %v", err)
+		if err = DBInit(db, lang, known_word_data); err != nil {
+			fmt.Println("Something went wrong", err)
+		}
+
+		if err = DBInsert(db, lang, "hobbit"); err != nil {
+			fmt.Println("Something went wrong", err)
+		}
+		found, _ := DBView(db, lang, "hobbit")
+		println("the word 'hobbit' was found: ", found)
+	} else {
+		cmd.ShowMessage(renderer, "[ERROR] vocabulary is disabled: "+db_err.Error())
 	}
-	found, _ := DBView(db, "hobbit")
-	println("the word 'hobbit' was found: ", found)
 	// DB stuff
 
 	running := true
@@ -436,6 +515,7 @@ func main() {
 			textbox.metadata[i].word_rects[j].Y = re[i].Y
 		}
 	}
+	textbox.UpdateWordStatus(db, lang)
 
 	scrollbar := &Scrollbar{drag: false, selected: false, rect: sdl.Rect{X: int32(LINE_LENGTH + X_OFFSET - 5), Y: 0, W: 5, H: 30}}
 
@@ -567,7 +647,9 @@ func main() {
 		font:    load_font(font_dir+"Inconsolata-Regular.ttf", 14),
 		text: []string{
 			"Open File",
+			"Chapters",
 			"Load Font",
+			"Review",
 			"Debug Menu",
 			"Properties",
 			"...More",
@@ -599,12 +681,39 @@ func main() {
 	}
 	sidebar.CenterTextRectX()
 
-	sidebar.callbacks["Open File"] = get_filedata
 	sidebar.callbacks["Load Font"] = func(cmd *CmdConsole) { cmd.show = !cmd.show }
 	sidebar.callbacks["Debug Menu"] = func() { println("Debug Menu") }
-	sidebar.callbacks["Properties"] = func() { println("Properties") }
 	sidebar.callbacks["...More"] = func() { println("...More") }
 
+	review := NewReviewMode(renderer, load_font(font_dir+"Inconsolata-Regular.ttf", TTF_FONT_SIZE))
+	sidebar.callbacks["Review"] = func() {
+		if err := review.Start(renderer, db, lang); err != nil {
+			cmd.ShowMessage(renderer, "[ERROR] review: "+err.Error())
+			return
+		}
+		cmd.show = false
+	}
+
+	stats := NewStatsPanel(renderer, load_font(font_dir+"Inconsolata-Regular.ttf", TTF_FONT_SIZE))
+	sidebar.callbacks["Properties"] = func() {
+		if err := stats.Open(renderer, db, config, text_dir, txt_list, flag_lang); err != nil {
+			cmd.ShowMessage(renderer, "[ERROR] stats: "+err.Error())
+			return
+		}
+		cmd.show = false
+	}
+
+	find := NewFindPanel(renderer, load_font(font_dir+"Inconsolata-Regular.ttf", TTF_FONT_SIZE))
+	jump_to_line := -1
+
+	// NOTE: the console stays open under the file panel, what's typed in it is the filter
+	files := NewFilePanel(renderer, load_font(font_dir+"Inconsolata-Regular.ttf", TTF_FONT_SIZE))
+	sidebar.callbacks["Open File"] = func() {
+		files.Open(renderer, file_dir)
+		cmd.MakeNULL()
+		cmd.show = true
+	}
+
 	//popup_a := NewPopup(10, 10, 5, byte('R'))
 
 	rendererInfo, err := renderer.GetInfo()
@@ -612,6 +721,229 @@ func main() {
 		panic(err)
 	}
 
+	// the console completes words from here, it's loaded the first time the user types in the console
+	var vocab_trie *TrieNode
+	load_vocab_trie := func() *TrieNode {
+		if vocab_trie != nil {
+			return vocab_trie
+		}
+		if db != nil {
+			trie, err := DBLoadTrie(db, lang)
+			if err != nil {
+				fmt.Println("[WARNING]", err)
+			}
+			vocab_trie = trie
+		}
+		if vocab_trie == nil {
+			vocab_trie = NewTrie()
+		}
+		for _, name := range cmd.CommandNames() {
+			vocab_trie.Insert(name)
+		}
+		for word := range known_word_data {
+			vocab_trie.Insert(word)
+		}
+		return vocab_trie
+	}
+	cmd.SetCompleter(load_vocab_trie)
+
+	// window_moved rebuilds what's made from the lines of text_window after it loaded other ones,
+	// the textbox still points into the old linemeta until START_ELEMENT is moved
+	window_moved := func() {
+		test_tokens = text_window.lines
+		ClearMetadata(&linemeta)
+		TEST_TOKENS_LEN = len(test_tokens)
+		linemeta = make([]LineMetaData, TEST_TOKENS_LEN)
+		generate_line_metadata(font, &linemeta, &test_tokens)
+		text_window.SetLineData(linemeta)
+		styles.MeasureWords(font, linemeta)
+
+		// the words of the new lines go into the db as we get to them
+		known_word_data = text_window.UniqueWords(filename)
+		if db != nil {
+			if err := DBInit(db, lang, known_word_data); err != nil {
+				fmt.Println("[WARNING]", err)
+			}
+		}
+		if vocab_trie != nil {
+			for word := range known_word_data {
+				vocab_trie.Insert(word)
+			}
+		}
+	}
+
+	// "chapters" lists the chapters of an EPUB, picking one jumps to it
+	chapters := NewChapterPanel(renderer, load_font(font_dir+"Inconsolata-Regular.ttf", TTF_FONT_SIZE))
+	cmd.Register("chapters", func(args []string) (string, error) {
+		if len(doc.chapters) == 0 {
+			return "", fmt.Errorf("'%s' has no chapters", filename)
+		}
+		chapters.Open(renderer, doc.chapters, text_window.Line(START_ELEMENT))
+		cmd.show = false
+		return fmt.Sprintf("[INFO] %d chapters in '%s'", len(doc.chapters), filename), nil
+	})
+	sidebar.callbacks["Chapters"] = func() { cmd.Execute(renderer, "chapters") }
+
+	// "find <word> [distance]" lists the vocabulary words that are close to word, see TrieNode.SearchWithin
+	cmd.Register("find", func(args []string) (string, error) {
+		if len(args) < 1 || len(args) > 2 {
+			return "", fmt.Errorf("usage: find <word> [distance]")
+		}
+		query := FoldWord(args[0])
+		k := FindMaxDistance(query)
+		if len(args) == 2 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 0 || n > 3 {
+				return "", fmt.Errorf("the distance has to be 0 to 3, got '%s'", args[1])
+			}
+			k = n
+		}
+		counts := WordCounts(linemeta)
+		var matches []FuzzyMatch
+		for _, m := range load_vocab_trie().SearchWithin(query, k) {
+			if _, is_cmd := cmd.commands[m.Word]; is_cmd && counts[m.Word] == 0 {
+				if db == nil {
+					continue // only in the trie for the completions
+				}
+				if found, _ := DBView(db, lang, m.Word); !found {
+					continue
+				}
+			}
+			matches = append(matches, m)
+		}
+		RankFuzzyMatches(matches, counts)
+		find.Open(renderer, query, matches, linemeta)
+		cmd.show = false
+		return fmt.Sprintf("[INFO] %d words within %d of '%s'", len(matches), k, query), nil
+	})
+
+	// "rules reload" re-reads the rules (and lemmas) of lang and tokenizes the text again,
+	// the lines stay where they are, only the words in them change
+	cmd.Register("rules", func(args []string) (string, error) {
+		if len(args) != 1 || args[0] != "reload" {
+			return "", fmt.Errorf("usage: rules reload")
+		}
+		// NOTE: a broken rules file leaves us with the defaults, the text has to match them too
+		rules_err := UseLanguage(config, lang)
+		ClearMetadata(&linemeta)
+		generate_line_metadata(font, &linemeta, &test_tokens)
+		text_window.SetLineData(linemeta)
+		styles.MeasureWords(font, linemeta)
+		// NOTE: the new word rects have no Y yet, put them back on the lines that are on screen
+		for i := 0; i < len(textbox.data); i++ {
+			textbox.metadata[i] = &linemeta[START_ELEMENT+i]
+			for j := 0; j < len(textbox.metadata[i].word_rects); j++ {
+				textbox.metadata[i].word_rects[j].Y = re[i].Y
+			}
+		}
+		textbox.UpdateWordStatus(db, lang)
+
+		known_word_data = text_window.UniqueWords(filename)
+		if vocab_trie != nil {
+			for word := range known_word_data {
+				vocab_trie.Insert(word)
+			}
+		}
+		if db != nil {
+			if err := DBInit(db, lang, known_word_data); err != nil {
+				return "", err
+			}
+		}
+		if rules_err != nil {
+			return "", rules_err
+		}
+		return fmt.Sprintf("[INFO] reloaded the '%s' rules, %d words", lang, len(known_word_data)), nil
+	})
+
+	// open_text swaps the text for the one in path, the wrapped lines, linemeta, the textbox
+	// and the scrollbar are built again from scratch, the font stays what it is
+	open_text := func(path string) error {
+		dir, name := filepath.Dir(path)+string(filepath.Separator), filepath.Base(path)
+		next_doc, err := OpenDocument(path, TextEncoding(dir, name))
+		if err != nil {
+			return err
+		}
+		qw, _, _ := font.SizeUTF8(" ")
+		next_window := NewTextWindow(next_doc, LINE_LENGTH, qw)
+		if len(next_window.lines) == 0 {
+			next_doc.Close()
+			return fmt.Errorf("'%s' is empty", path)
+		}
+		doc.Close()
+		doc, text_window = next_doc, next_window
+		file_dir, filename = dir, name
+		lang = TextLanguage(file_dir, filename, flag_lang)
+		lang_err := UseLanguage(config, lang)
+		vocab_trie = nil // NOTE: it's the vocabulary of the old language
+		cmd.SetCompleter(load_vocab_trie)
+		find.Close()
+		chapters.Close()
+		X_OFFSET = X_OFFSET_TEXT + gutter.Width(doc.cues)
+		scrollbar.rect.X = int32(LINE_LENGTH + X_OFFSET - 5)
+
+		test_tokens = text_window.lines
+		textbox.MakeNULL()
+		ClearMetadata(&linemeta)
+		TEST_TOKENS_LEN = len(test_tokens)
+		linemeta = make([]LineMetaData, TEST_TOKENS_LEN)
+		generate_line_metadata(font, &linemeta, &test_tokens)
+		text_window.SetLineData(linemeta)
+		styles.MeasureWords(font, linemeta)
+
+		qsize = int(math.RoundToEven(float64(WIN_H)/float64(font.Height()))) + 1
+		if len(linemeta) < qsize {
+			qsize = len(linemeta)
+		}
+		START_ELEMENT = 0
+		NEXT_ELEMENT = qsize
+
+		textbox.fmt.Free()
+		textbox = TextBox{
+			data:       make([]*sdl.Texture, qsize),
+			data_rects: make([]sdl.Rect, qsize),
+			metadata:   make([]*LineMetaData, qsize),
+		}
+		for i := 0; i < qsize; i++ {
+			textbox.metadata[i] = &linemeta[i]
+		}
+		textbox.CreateEmpty(renderer, font, sdl.Color{R: 0, G: 0, B: 0, A: 255})
+		textbox.Update(font, test_tokens[START_ELEMENT:NEXT_ELEMENT], sdl.Color{R: 0, G: 0, B: 0, A: 255})
+
+		re = make([]sdl.Rect, qsize)
+		rey = genY(font, qsize)
+		for i := 0; i < qsize; i++ {
+			re[i] = sdl.Rect{X: int32(X_OFFSET), Y: int32(rey[i]), W: int32(LINE_LENGTH), H: int32(font.Height())}
+			for j := 0; j < len(textbox.metadata[i].word_rects); j++ {
+				textbox.metadata[i].word_rects[j].Y = re[i].Y
+			}
+		}
+		textbox.UpdateWordStatus(db, lang)
+		scrollbar.CalcPos(text_window.Position(NEXT_ELEMENT))
+		inc_dbg_str = true
+
+		known_word_data = text_window.UniqueWords(filename)
+		if db != nil {
+			if err := DBInit(db, lang, known_word_data); err != nil {
+				return err
+			}
+		}
+		println("[INFO] opened", path, "vocabulary language:", lang)
+		return lang_err
+	}
+
+	// "open" shows the file panel, "open <path>" opens path right away
+	cmd.Register("open", func(args []string) (string, error) {
+		if len(args) == 0 {
+			files.Open(renderer, file_dir)
+			return "", nil
+		}
+		if err := open_text(strings.Join(args, " ")); err != nil {
+			return "", err
+		}
+		cmd.show = false
+		return fmt.Sprintf("[INFO] opened '%s' (%s), %d lines", filename, lang, TEST_TOKENS_LEN), nil
+	})
+
 	for running {
 		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
 			switch t := event.(type) {
@@ -704,14 +1036,14 @@ func main() {
 				switch t.Type {
 				case sdl.MOUSEBUTTONDOWN:
 				case sdl.MOUSEBUTTONUP:
-					if sidebar.highlight {
+					if t.Button == sdl.BUTTON_LEFT && !cmd.show && !review.show && !stats.show && !find.show && !chapters.show {
+						textbox.ChangeHoveredWordStatus(db, lang, NextWordStatus)
+					}
+					if sidebar.highlight && cmd.show { // the sidebar is only drawn with the console
 						sidebar.clicked = true
 					}
 					if sidebar.clicked {
 						switch txt := sidebar.text[sidebar.buttonindex]; txt {
-						case "Open File":
-							bytes := sidebar.callbacks[txt].(func(string, string) []byte)(text_dir, "Hanyu.txt") // temp
-							println(len(bytes))
 						case "Load Font":
 							sidebar.callbacks[txt].(func(*CmdConsole))(&cmd) // cmd.show = true
 						default:
@@ -736,8 +1068,65 @@ func main() {
 			case *sdl.TextInputEvent:
 				if cmd.show {
 					cmd.WriteChar(renderer, t.Text[0])
+					if files.show {
+						files.SetFilter(renderer, cmd.input_buffer.String())
+					}
 				}
 			case *sdl.KeyboardEvent:
+				if review.show { // review mode takes every key while it's on
+					if t.Type == sdl.KEYUP {
+						if err := review.HandleKey(renderer, t.Keysym.Sym); err != nil {
+							cmd.ShowMessage(renderer, "[ERROR] review: "+err.Error())
+						}
+					}
+					continue
+				}
+				if stats.show {
+					if t.Type == sdl.KEYUP {
+						stats.HandleKey(renderer, t.Keysym.Sym)
+					}
+					continue
+				}
+				if find.show {
+					if t.Type == sdl.KEYUP {
+						if line, ok := find.HandleKey(renderer, t.Keysym.Sym); ok {
+							jump_to_line = line
+						}
+					}
+					continue
+				}
+				if chapters.show {
+					if t.Type == sdl.KEYUP {
+						if line, ok := chapters.HandleKey(renderer, t.Keysym.Sym); ok {
+							jump, moved := text_window.Seek(line)
+							if moved {
+								window_moved()
+							}
+							jump_to_line = jump
+						}
+					}
+					continue
+				}
+				if files.show {
+					if t.Keysym.Sym == sdl.K_BACKSPACE && (t.Type == sdl.KEYUP || t.Repeat > 0) {
+						cmd.Reset(renderer)
+						files.SetFilter(renderer, cmd.input_buffer.String())
+					}
+					if t.Type == sdl.KEYUP {
+						path, ok := files.HandleKey(renderer, t.Keysym.Sym)
+						if t.Keysym.Sym == sdl.K_RETURN || t.Keysym.Sym == sdl.K_ESCAPE {
+							cmd.MakeNULL() // the filter was for the directory we just left
+						}
+						if ok {
+							if err := open_text(path); err != nil {
+								cmd.ShowMessage(renderer, "[ERROR] open: "+err.Error())
+							} else {
+								cmd.show = false
+							}
+						}
+					}
+					continue
+				}
 				if cmd.show {
 					if t.Keysym.Sym == sdl.K_BACKSPACE {
 						if t.Repeat > 0 {
@@ -761,29 +1150,52 @@ func main() {
 					switch t.Keysym.Sym {
 					case sdl.KEYDOWN:
 					case sdl.K_TAB:
-						cmd.show = !cmd.show
+						// NOTE: with a drop-down open Tab takes the completion, Escape closes the console
+						if !cmd.show || !cmd.AcceptCompletion(renderer) {
+							cmd.show = !cmd.show
+						}
 					case sdl.K_BACKSPACE:
 						cmd.Reset(renderer)
 					case sdl.K_RETURN:
 						if cmd.show {
-							if len(cmd.input_buffer.String()) > 0 {
+							line := cmd.input_buffer.String()
+							if len(line) > 0 {
 								cmd.MakeNULL()
 							}
+							cmd.ShowMessage(renderer, "")
+							cmd.Execute(renderer, line)
+						}
+					case sdl.K_1, sdl.K_2, sdl.K_3, sdl.K_4:
+						if !cmd.show {
+							status := WordStatus(t.Keysym.Sym - sdl.K_1) // 1: new, 2: learning, 3: known, 4: ignored
+							textbox.ChangeHoveredWordStatus(db, lang, func(WordStatus) WordStatus { return status })
 						}
 					case sdl.K_UP:
-						move_text_up = true
+						if cmd.show && cmd.HasCompletions() {
+							cmd.SelectCompletion(-1)
+						} else {
+							move_text_up = true
+						}
 					case sdl.K_DOWN:
-						move_text_down = true
+						if cmd.show && cmd.HasCompletions() {
+							cmd.SelectCompletion(1)
+						} else {
+							move_text_down = true
+						}
 					case sdl.K_RIGHT:
 						page_down = true
 					case sdl.K_LEFT:
 						page_up = true
 					case sdl.K_d: // TESTING RESIZING FONTS
+						if cmd.show { // typing in the console
+							break
+						}
 						test_font_size -= 1
 						font = reload_font(font, font_dir+test_font_name, test_font_size)
+						styles.Load(font_dir, test_font_name, test_font_size)
 						qw, _, _ := font.SizeUTF8(" ")
-						test_tokens = nil
-						test_tokens = NewWrapLines(file_data, LINE_LENGTH, qw)
+						text_window.Wrap(LINE_LENGTH, qw)
+						test_tokens = text_window.lines
 						textbox.MakeNULL() // could this be a problem later?
 
 						ClearMetadata(&linemeta)
@@ -791,6 +1203,8 @@ func main() {
 						TEST_TOKENS_LEN = len(test_tokens)
 						linemeta = make([]LineMetaData, TEST_TOKENS_LEN)
 						generate_line_metadata(font, &linemeta, &test_tokens)
+						text_window.SetLineData(linemeta)
+						styles.MeasureWords(font, linemeta)
 
 						prev_qsize := qsize
 						qsize = int(math.RoundToEven(float64(WIN_H)/float64(font.Height()))) + 1
@@ -833,12 +1247,17 @@ func main() {
 								textbox.metadata[i].word_rects[j].Y = re[i].Y
 							}
 						}
+						textbox.UpdateWordStatus(db, lang)
 					case sdl.K_f: // TESTING RESIZING FONTS
+						if cmd.show { // typing in the console
+							break
+						}
 						test_font_size += 1
 						font = reload_font(font, font_dir+test_font_name, test_font_size)
+						styles.Load(font_dir, test_font_name, test_font_size)
 						qw, _, _ := font.SizeUTF8(" ")
-						test_tokens = nil
-						test_tokens = NewWrapLines(file_data, LINE_LENGTH, qw)
+						text_window.Wrap(LINE_LENGTH, qw)
+						test_tokens = text_window.lines
 						textbox.MakeNULL() // could this be a problem later?
 
 						ClearMetadata(&linemeta)
@@ -846,6 +1265,8 @@ func main() {
 						TEST_TOKENS_LEN = len(test_tokens)
 						linemeta = make([]LineMetaData, TEST_TOKENS_LEN)
 						generate_line_metadata(font, &linemeta, &test_tokens)
+						text_window.SetLineData(linemeta)
+						styles.MeasureWords(font, linemeta)
 
 						prev_qsize := qsize
 						qsize = int(math.RoundToEven(float64(WIN_H)/float64(font.Height()))) + 1
@@ -884,10 +1305,15 @@ func main() {
 								textbox.metadata[i].word_rects[j].Y = re[i].Y
 							}
 						}
+						textbox.UpdateWordStatus(db, lang)
 					}
 				}
-				if t.Keysym.Sym == sdl.K_ESCAPE {
-					running = false
+				if t.Type == sdl.KEYUP && t.Keysym.Sym == sdl.K_ESCAPE {
+					if cmd.show {
+						cmd.show = false
+					} else {
+						running = false
+					}
 				}
 			default:
 				continue
@@ -1011,7 +1437,10 @@ func main() {
 		//draw_rounded_rect_with_border_filled(renderer, &multiline_texture.bg_rect, &COLOR_IRON)
 		//renderer.Copy(multiline_texture.texture, nil, &multiline_texture.bg_rect)
 
+		textbox.UpdateStyles(&styles, font, sdl.Color{R: 0, G: 0, B: 0, A: 255})
+		gutter.Draw(renderer, textbox.metadata[:textbox.MetadataSize()], textbox.data_rects)
 		for i := 0; i < textbox.MetadataSize(); i++ {
+			textbox.DrawWordStatus(renderer, i)
 			renderer.Copy(textbox.data[i], nil, &textbox.data_rects[i])
 			for j := 0; j < len(textbox.metadata[i].mouse_over_word); j++ {
 				if textbox.metadata[i].mouse_over_word[j] {
@@ -1082,14 +1511,31 @@ func main() {
 			//draw_rect_without_border(renderer, &sdl.Rect{0, 0, WIN_W, WIN_W}, &sdl.Color{255, 255, 255, 100})
 		}
 
+		// NOTE: no key moves more than a page, so with two pages of margin we never run off the window
+		// a jump has loaded the window it needs already and START_ELEMENT is still an index into the old one
+		if jump_to_line < 0 {
+			if start, moved := text_window.Slide(START_ELEMENT, qsize*2); moved {
+				NEXT_ELEMENT += start - START_ELEMENT
+				START_ELEMENT = start
+				window_moved()
+				for i := 0; i < len(textbox.data); i++ {
+					textbox.metadata[i] = &linemeta[START_ELEMENT+i]
+					for j := 0; j < len(textbox.metadata[i].word_rects); j++ {
+						textbox.metadata[i].word_rects[j].Y = re[i].Y
+					}
+				}
+				textbox.UpdateWordStatus(db, lang)
+			}
+		}
+
 		if move_text_down {
 			move_text_down = false
-			if NEXT_ELEMENT <= TEST_TOKENS_LEN {
+			if NEXT_ELEMENT < TEST_TOKENS_LEN {
 				NEXT_ELEMENT += 1
 				START_ELEMENT += 1
 				textbox.Clear(font)
 				textbox.Update(font, test_tokens[START_ELEMENT:NEXT_ELEMENT], sdl.Color{R: 0, G: 0, B: 0, A: 255})
-				scrollbar.CalcPos(NEXT_ELEMENT, TEST_TOKENS_LEN)
+				scrollbar.CalcPos(text_window.Position(NEXT_ELEMENT))
 				inc_dbg_str = true
 				for i := 0; i < len(textbox.data); i++ {
 					textbox.metadata[i] = &linemeta[START_ELEMENT+i]
@@ -1097,6 +1543,7 @@ func main() {
 						textbox.metadata[i].word_rects[j].Y = re[i].Y
 					}
 				}
+				textbox.UpdateWordStatus(db, lang)
 			}
 			// test
 			smooth.animate = true
@@ -1124,7 +1571,7 @@ func main() {
 				START_ELEMENT -= 1
 				textbox.Clear(font)
 				textbox.Update(font, test_tokens[START_ELEMENT:NEXT_ELEMENT], sdl.Color{R: 0, G: 0, B: 0, A: 255})
-				scrollbar.CalcPos(NEXT_ELEMENT, TEST_TOKENS_LEN)
+				scrollbar.CalcPos(text_window.Position(NEXT_ELEMENT))
 				inc_dbg_str = true
 				for i := 0; i < len(textbox.data); i++ {
 					textbox.metadata[i] = &linemeta[START_ELEMENT+i]
@@ -1132,6 +1579,7 @@ func main() {
 						textbox.metadata[i].word_rects[j].Y = re[i].Y
 					}
 				}
+				textbox.UpdateWordStatus(db, lang)
 			}
 
 			smooth.animate = true
@@ -1169,6 +1617,28 @@ func main() {
 					textbox.metadata[i].word_rects[j].Y = re[i].Y
 				}
 			}
+			textbox.UpdateWordStatus(db, lang)
+		}
+
+		if jump_to_line >= 0 {
+			// the line goes at the top of the page, unless we are at the end of the text
+			inc_dbg_str = true
+			START_ELEMENT = jump_to_line
+			if START_ELEMENT > TEST_TOKENS_LEN-qsize {
+				START_ELEMENT = TEST_TOKENS_LEN - qsize
+			}
+			NEXT_ELEMENT = START_ELEMENT + qsize
+			jump_to_line = -1
+			textbox.Clear(font)
+			textbox.Update(font, test_tokens[START_ELEMENT:NEXT_ELEMENT], sdl.Color{R: 0, G: 0, B: 0, A: 255})
+			for i := 0; i < len(textbox.data); i++ {
+				textbox.metadata[i] = &linemeta[START_ELEMENT+i]
+				for j := 0; j < len(textbox.metadata[i].word_rects); j++ {
+					textbox.metadata[i].word_rects[j].Y = re[i].Y
+				}
+			}
+			textbox.UpdateWordStatus(db, lang)
+			scrollbar.CalcPos(text_window.Position(NEXT_ELEMENT))
 		}
 
 		if page_up {
@@ -1188,6 +1658,7 @@ func main() {
 					textbox.metadata[i].word_rects[j].Y = re[i].Y
 				}
 			}
+			textbox.UpdateWordStatus(db, lang)
 		}
 
 		if wrap_line {
@@ -1216,12 +1687,15 @@ func main() {
 						if int32(gfonts.current_font_w) >= gfonts.fonts[i].width && int32(gfonts.current_font_h) >= gfonts.fonts[i].height {
 							font = reload_font(font, font_dir+gfonts.fonts[i].name, test_font_size)
 							test_font_name = gfonts.fonts[i].name
+							styles.Load(font_dir, test_font_name, test_font_size)
 							textbox.MakeNULL()
 							textbox.CreateEmpty(renderer, font, sdl.Color{R: 0, G: 0, B: 0, A: 255})
 							textbox.Update(font, test_tokens[START_ELEMENT:NEXT_ELEMENT], sdl.Color{R: 0, G: 0, B: 0, A: 255})
 
 							ClearMetadata(&linemeta)
 							generate_line_metadata(font, &linemeta, &test_tokens)
+							text_window.SetLineData(linemeta)
+							styles.MeasureWords(font, linemeta)
 
 							for i := 0; i < len(textbox.data); i++ {
 								textbox.metadata[i] = &linemeta[START_ELEMENT+i]
@@ -1235,6 +1709,7 @@ func main() {
 									textbox.metadata[i].word_rects[j].Y = re[i].Y
 								}
 							}
+							textbox.UpdateWordStatus(db, lang)
 						}
 						print_word = false
 					}
@@ -1250,6 +1725,11 @@ func main() {
 			draw_rect_with_border_filled(renderer, &dbg_rect, &sdl.Color{R: 180, G: 123, B: 55, A: 255})
 			renderer.Copy(dbg_ttf, nil, &dbg_rect)
 
+			cmd.DrawMessage(renderer)
+			if !files.show {
+				cmd.DrawCompletions(renderer)
+			}
+
 			sidebar.Draw(renderer)
 		}
 
@@ -1260,6 +1740,22 @@ func main() {
 
 		//popup_a.Draw(renderer)
 
+		if review.show {
+			review.Draw(renderer)
+		}
+		if stats.show {
+			stats.Draw(renderer)
+		}
+		if find.show {
+			find.Draw(renderer)
+		}
+		if files.show {
+			files.Draw(renderer)
+		}
+		if chapters.show {
+			chapters.Draw(renderer)
+		}
+
 		renderer.Present()
 
 		//NOTE: this is not for framerate independance
@@ -1283,11 +1779,14 @@ func main() {
 	color_picker.texture.Destroy()
 	color_picker.font.Close()
 
-	if cmd.ttf_texture != nil {
-		cmd.ttf_texture.Destroy()
-		cmd.ttf_texture = nil
-	}
-	cmd.font.Close()
+	cmd.Destroy()
+	review.Destroy()
+	stats.Destroy()
+	find.Destroy()
+	files.Destroy()
+	chapters.Destroy()
+	gutter.Destroy()
+	styles.Close()
 
 	dbg_ttf.Destroy()
 
@@ -1338,29 +1837,6 @@ func main() {
 	// PROFILING SNIPPET
 }
 
-func GetBOM(bom []byte, file *os.File) uint8 {
-	switch b0, b1, b2, b3 := bom[0], bom[1], bom[2], bom[3]; {
-	case b0 == 0xEF && b1 == 0xBB && b2 == 0xBF:
-		file.Seek(3, os.SEEK_SET)
-		return BOM_UTF8
-	case b0 == 0xFE && b1 == 0xFF:
-		file.Seek(3, os.SEEK_SET)
-		return BOM_UTF16_BE
-	case b0 == 0xFF && b1 == 0xFE:
-		file.Seek(3, os.SEEK_SET)
-		return BOM_UTF16_LE
-	case b0 == 0x00 && b1 == 0x00 && b2 == 0xFE && b3 == 0xFF:
-		// no need to file.Seek
-		return BOM_UTF32_BE
-	case b0 == 0xFE && b1 == 0xFF && b2 == 0x00 && b3 == 0x00:
-		// no need to file.Seek
-		return BOM_UTF32_LE
-	default: // No Encoding or Unsupported encodings
-		file.Seek(0, os.SEEK_SET)
-		return BOM_NONE_OR_UNSUPPORTED
-	}
-}
-
 func load_font(name string, size int) *ttf.Font {
 	var font *ttf.Font
 	var err error
@@ -1430,7 +1906,7 @@ func generate_line_metadata(font *ttf.Font, dest *[]LineMetaData, tokens *[]stri
 func populate_line_metadata(line *LineMetaData, line_text string, font *ttf.Font, x int, y int) {
 	assert_if(len(line_text) == 0)
 
-	text := strings.Split(line_text, " ")
+	text, glued := SplitLineWords(line_text)
 	text_len := len(text)
 
 	if text[text_len-1] == "" { // guard against an empty ""
@@ -1460,6 +1936,12 @@ func populate_line_metadata(line *LineMetaData, line_text string, font *ttf.Font
 	line.words = make([]string, text_len)
 	copy(line.words, text)
 
+	line.word_keys = make([]string, text_len)
+	line.word_status = make([]WordStatus, text_len)
+	for index := 0; index < text_len; index++ {
+		line.word_keys[index] = NormalizeWord(text[index])
+	}
+
 	//f := func(r rune) bool { //   return r < 'A' || r > 'z' //}
 	//f := func(r rune) bool { //	return r > 0x7F // 127 //}
 
@@ -1482,24 +1964,34 @@ func populate_line_metadata(line *LineMetaData, line_text string, font *ttf.Font
 		//	//fmt.Printf("%c %v %d \n", r, size, font_has_glyph)
 		//	str = str[size:]
 		//}
-		if strings.IndexFunc(text[index], func(r rune) bool { return r > 0x7f }) != -1 { //-1 is none is found
+		space := x
+		if glued[index] {
+			space = 0 // segmented CJK, the next word follows right away
+		}
+		if strings.IndexFunc(text[index], tokenizer.IsCJK) != -1 {
+			// NOTE: CJK glyphs are about twice as wide as x even in a fixed width font
+			ix, _, _ = font.SizeUTF8(text[index])
+			line.word_rects[index] = sdl.Rect{X: int32(move_x), Y: int32(-y), W: int32(ix), H: int32(y)}
+			move_x += (ix + space)
+		} else if strings.IndexFunc(text[index], func(r rune) bool { return r > 0x7f }) != -1 { //-1 is none is found
 			//r, size := utf8.DecodeRune(text[index][pos]) //println(r, size)
 			//fmt.Println("non-Ascii found: ", text[index])
 			//fmt.Println(text[index], UTF8_CharCount(text[index]))
 			ix = x * UTF8_CharCount(text[index])
 			line.word_rects[index] = sdl.Rect{X: int32(move_x), Y: int32(-y), W: int32(ix), H: int32(y)}
-			move_x += (ix + x)
+			move_x += (ix + space)
 		} else {
 			//fmt.Println("This should be ASCII then", text[index])
 			ix = x * len(text[index])
 			line.word_rects[index] = sdl.Rect{X: int32(move_x), Y: int32(-y), W: int32(ix), H: int32(y)}
-			move_x += (ix + x)
+			move_x += (ix + space)
 		}
 		//ix = x * len(text[index])
 		//line.word_rects[index] = sdl.Rect{X: int32(move_x), Y: int32(-y), W: int32(ix), H: int32(y)}
 		//move_x += (ix + x)
 	}
 	text = nil
+	glued = nil
 }
 
 func UTF8_CharCount(text string) int {
@@ -1516,6 +2008,8 @@ func UTF8_CharCount(text string) int {
 func ClearMetadata(line *[]LineMetaData) {
 	for i := 0; i < len((*line)); i++ {
 		(*line)[i].words = nil
+		(*line)[i].word_keys = nil
+		(*line)[i].word_status = nil
 		(*line)[i].word_rects = nil
 		(*line)[i].mouse_over_word = nil
 	}
@@ -1717,7 +2211,18 @@ func GetSlice(s string, end int) func() (string, bool) {
 			_, size := utf8.DecodeLastRuneInString(s[start:last])
 			last = last - size
 		}
-		last = strings.LastIndex(s[:last], " ") + 1 // +1 is to remove extra space
+		if space := strings.LastIndex(s[start:last], " "); space >= 0 {
+			last = start + space + 1 // +1 is to remove extra space
+		} else {
+			// NOTE: a word longer than the line has no space to break at, it's cut where the line ends
+			for last > start && !utf8.RuneStart(s[last]) {
+				last -= 1
+			}
+			if last == start {
+				_, size := utf8.DecodeRuneInString(s[start:])
+				last += size
+			}
+		}
 		result := s[start:last]
 		start = last
 		return result, false
@@ -2013,6 +2518,81 @@ func (tbox *TextBox) MetadataSize() int {
 	return result
 }
 
+// UpdateWordStatus does one batched db lookup for every word on the current page.
+// Call it whenever tbox.metadata points to different lines.
+func (tbox *TextBox) UpdateWordStatus(db *bolt.DB, lang string) {
+	if db == nil {
+		return
+	}
+	var keys []string
+	for i := 0; i < tbox.MetadataSize(); i++ {
+		keys = append(keys, tbox.metadata[i].word_keys...)
+	}
+	status, err := DBGetStatuses(db, lang, keys)
+	if err != nil {
+		fmt.Println(err)
+	}
+	for i := 0; i < tbox.MetadataSize(); i++ {
+		for j, key := range tbox.metadata[i].word_keys {
+			tbox.metadata[i].word_status[j] = status[key] // missing words are WORD_STATUS_NEW
+		}
+	}
+}
+
+// SetWordStatus updates every visible occurrence of key without going to the db,
+// the textures don't change so there is nothing to rebuild.
+func (tbox *TextBox) SetWordStatus(key string, status WordStatus) {
+	for i := 0; i < tbox.MetadataSize(); i++ {
+		for j := range tbox.metadata[i].word_keys {
+			if tbox.metadata[i].word_keys[j] == key {
+				tbox.metadata[i].word_status[j] = status
+			}
+		}
+	}
+}
+
+func (tbox *TextBox) HoveredWord() (int, int, bool) {
+	for i := 0; i < tbox.MetadataSize(); i++ {
+		for j := range tbox.metadata[i].mouse_over_word {
+			if tbox.metadata[i].mouse_over_word[j] && tbox.metadata[i].word_keys[j] != "" {
+				return i, j, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// ChangeHoveredWordStatus writes next(current status) of the hovered word through to the db.
+func (tbox *TextBox) ChangeHoveredWordStatus(db *bolt.DB, lang string, next func(WordStatus) WordStatus) {
+	i, j, ok := tbox.HoveredWord()
+	if !ok || db == nil {
+		return
+	}
+	key := tbox.metadata[i].word_keys[j]
+	status := next(tbox.metadata[i].word_status[j])
+	err := DBUpdateWord(db, lang, key, func(rec *WordRecord) error {
+		rec.SetStatus(status, time.Now())
+		return nil
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	tbox.SetWordStatus(key, status)
+}
+
+func (tbox *TextBox) DrawWordStatus(renderer *sdl.Renderer, line int) {
+	meta := tbox.metadata[line]
+	for j := range meta.word_status {
+		if meta.word_keys[j] == "" || int(meta.word_status[j]) >= len(WORD_STATUS_COLORS) {
+			continue
+		}
+		if c := WORD_STATUS_COLORS[meta.word_status[j]]; c.A > 0 {
+			draw_rect_without_border(renderer, &meta.word_rects[j], &c)
+		}
+	}
+}
+
 func (tbox *TextBox) CreateEmpty(renderer *sdl.Renderer, font *ttf.Font, color sdl.Color) {
 	surface, _ := font.RenderUTF8Blended(" ", color)
 	if tbox.fmt == nil {
@@ -2055,6 +2635,9 @@ func (tbox *TextBox) Update(font *ttf.Font, text []string, color sdl.Color) {
 	}
 
 	for i := 0; i < tbox.MetadataSize(); i++ {
+		if i < len(tbox.styled) {
+			tbox.styled[i] = nil
+		}
 		if text[i] != "\n" {
 			surface, _ := font.RenderUTF8Blended(text[i], color)
 			converted, _ := surface.Convert(tbox.fmt, 0)
@@ -2085,10 +2668,58 @@ func (tbox *TextBox) Clear(font *ttf.Font) {
 		copy(bytes, converted.Pixels())
 		tbox.data[i].Unlock()
 	}
+	for i := range tbox.styled {
+		tbox.styled[i] = nil
+	}
 	surface.Free()
 	converted.Free()
 }
 
+// UpdateStyles draws the lines that have styles again, with their own fonts and colors.
+// Update draws every line with one font, so it's called on every frame and only redraws
+// the lines that weren't drawn with their styles yet.
+func (tbox *TextBox) UpdateStyles(styles *StyleFonts, font *ttf.Font, color sdl.Color) {
+	if len(tbox.styled) != len(tbox.data) {
+		tbox.styled = make([]*LineMetaData, len(tbox.data))
+	}
+	for i := 0; i < tbox.MetadataSize(); i++ {
+		meta := tbox.metadata[i]
+		if meta.runs == nil || tbox.styled[i] == meta || tbox.data[i] == nil {
+			continue
+		}
+		tbox.styled[i] = meta
+		surface, err := styles.Render(font, meta.runs, color)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if surface.W == 0 || surface.H == 0 {
+			surface.Free()
+			continue
+		}
+		converted, _ := surface.Convert(tbox.fmt, 0)
+		if pixels, _, err := tbox.data[i].Lock(nil); err == nil {
+			for j := range pixels {
+				pixels[j] = 0 // NOTE: the plain line can be wider than the styled one
+			}
+			tbox.data[i].Unlock()
+		}
+		w, h := converted.W, converted.H
+		if w > int32(LINE_LENGTH) {
+			w = int32(LINE_LENGTH)
+		}
+		if h > tbox.texture_h {
+			h = tbox.texture_h
+		}
+		err = tbox.data[i].Update(&sdl.Rect{X: 0, Y: 0, W: w, H: h}, unsafe.Pointer(&converted.Pixels()[0]), int(converted.Pitch))
+		if err != nil {
+			fmt.Println(err)
+		}
+		surface.Free()
+		converted.Free()
+	}
+}
+
 func (tbox *TextBox) MakeNULL() {
 	for i := 0; i < len(tbox.data); i++ {
 		if tbox.data[i] != nil {
@@ -2096,6 +2727,7 @@ func (tbox *TextBox) MakeNULL() {
 			tbox.data[i] = nil
 		}
 	}
+	tbox.styled = nil
 }
 
 func (CP *ColorPicker) CenterRectAB() {
@@ -2234,6 +2866,9 @@ func (ML *MultiLine) Clear(font *ttf.Font) {
 
 	// TODO: do we need to do converted pixels here?? -> NO
 	bytes, _, _ := ML.texture.Lock(nil)
+	for i := range bytes { // NOTE: the old content isn't guaranteed to be gone after Lock
+		bytes[i] = 0
+	}
 	copy(bytes, converted.Pixels())
 	ML.texture.Unlock()
 
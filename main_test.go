@@ -2,8 +2,11 @@ package main
 
 import (
 	"bytes"
+	"os"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
 var br [][]byte
@@ -35,6 +38,61 @@ func BenchmarkSplitToStrings(b *testing.B) {
 	sr = r
 }
 
+// NOTE: big_text is a stand-in for a novel collection, the real thing is a few hundred MB
+func big_text(b *testing.B) string {
+	line := "It was the best of times, it was the worst of times, it was the age of wisdom, it was the age of foolishness\n"
+	path := b.TempDir() + "/big.txt"
+	if err := os.WriteFile(path, bytes.Repeat([]byte(line), (64<<20)/len(line)), FILE_MODE_RW); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+func BenchmarkLoadWholeText(b *testing.B) {
+	path := big_text(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		text, err := LoadText("", path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		sr = NewWrapLines(text, 800, 10)
+	}
+}
+
+func BenchmarkLoadTextWindow(b *testing.B) {
+	path := big_text(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		doc, err := OpenDocument(path, "")
+		if err != nil {
+			b.Fatal(err)
+		}
+		sr = NewTextWindow(doc, 800, 10).lines
+		doc.Close()
+	}
+}
+
+func BenchmarkSlideTextWindow(b *testing.B) {
+	doc, err := OpenDocument(big_text(b), "")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer doc.Close()
+	tw := NewTextWindow(doc, 800, 10)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if tw.AtEnd() {
+			tw.Load(0)
+		}
+		tw.Slide(len(tw.lines)-1, 60)
+	}
+	sr = tw.lines
+}
+
 //go:noinline
 func BenchmarkEaseInQuad(b *testing.B) {
 	var out float32
@@ -211,6 +269,33 @@ func TestDoWrapLines(t *testing.T) {
 	_ = result
 }
 
+func TestNewWrapLinesLongWord(t *testing.T) {
+	url := "https://example.com/" + strings.Repeat("a", 60)
+	test := []string{
+		url,
+		"voir " + url + " pour la suite",
+		"un mot " + strings.Repeat("très", 20) + " long",
+	}
+	for _, input := range test {
+		done := make(chan []string)
+		go func() { done <- NewWrapLines(input, 200, 10) }()
+		var result []string
+		select {
+		case result = <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("TestNewWrapLinesLongWord(%q) failed: NewWrapLines never returned", input)
+		}
+		if strings.Join(result, "") != input {
+			t.Errorf("TestNewWrapLinesLongWord failed: got (%q) expected the pieces of (%q)", result, input)
+		}
+		for _, line := range result {
+			if utf8.RuneCountInString(line) > 19 || !utf8.ValidString(line) {
+				t.Errorf("TestNewWrapLinesLongWord failed: (%q) doesn't fit in 19 characters", line)
+			}
+		}
+	}
+}
+
 //go:noinline
 func BenchmarkDoWrapLine(b *testing.B) { // add sub benchmarks here
 	var result []string
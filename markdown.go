@@ -0,0 +1,316 @@
+package main
+
+import (
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A small part of Markdown: # headings, **bold**, *italic*, > quotes and - lists.
+// The markup is taken out of the text, what's left goes through the wrapping and the word
+// lookups like any other text, the styles are kept as byte ranges of every line (StyleSpan)
+// and cut into StyledRuns for every wrapped line, which TextBox.UpdateStyles draws.
+
+type TextStyle uint8
+
+const (
+	STYLE_BOLD TextStyle = 1 << iota
+	STYLE_ITALIC
+	STYLE_HEADING
+	STYLE_QUOTE
+)
+
+// the styles that need another font, the rest is only color
+const STYLE_FONT_MASK = STYLE_BOLD | STYLE_ITALIC
+
+type StyleSpan struct {
+	Start int
+	End   int
+	Style TextStyle
+}
+
+type StyledRun struct {
+	Text  string
+	Style TextStyle
+}
+
+func is_markdown(filename string) bool {
+	ext := strings.ToLower(path.Ext(filename))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// ParseMarkdown returns the lines of text without the markup and the styled parts of every line.
+// NOTE: code blocks are kept as they are, without the ``` lines
+func ParseMarkdown(text string) ([]string, [][]StyleSpan) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var lines []string
+	var spans [][]StyleSpan
+	in_code := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			in_code = !in_code
+			continue
+		}
+		if in_code {
+			lines = append(lines, line)
+			spans = append(spans, nil)
+			continue
+		}
+		plain, styles := ParseMarkdownLine(line)
+		lines = append(lines, plain)
+		spans = append(spans, styles)
+	}
+	return lines, spans
+}
+
+// ParseMarkdownLine does one line, the block markup at the start of it and the emphasis in it.
+func ParseMarkdownLine(line string) (string, []StyleSpan) {
+	trimmed := strings.TrimSpace(line)
+	if is_rule(trimmed) {
+		return "", nil
+	}
+	if level := heading_level(trimmed); level > 0 {
+		text := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(trimmed[level:]), "#"))
+		plain, styles := parse_emphasis(text)
+		return plain, with_style(styles, len(plain), STYLE_BOLD|STYLE_HEADING)
+	}
+	if strings.HasPrefix(trimmed, ">") {
+		for strings.HasPrefix(trimmed, ">") {
+			trimmed = strings.TrimSpace(trimmed[1:]) // NOTE: ">> nested" quotes are just quotes
+		}
+		plain, styles := parse_emphasis(trimmed)
+		return plain, with_style(styles, len(plain), STYLE_ITALIC|STYLE_QUOTE)
+	}
+	if indent, marker, rest, ok := list_item(line); ok {
+		prefix := indent + marker + " "
+		plain, styles := parse_emphasis(rest)
+		for i := range styles {
+			styles[i].Start += len(prefix)
+			styles[i].End += len(prefix)
+		}
+		return prefix + plain, styles
+	}
+	return parse_emphasis(line)
+}
+
+// is_rule is "---", "***" or "___", a line across the page that we leave empty.
+func is_rule(line string) bool {
+	line = strings.ReplaceAll(line, " ", "")
+	if len(line) < 3 {
+		return false
+	}
+	return strings.Count(line, line[:1]) == len(line) && strings.Contains("-*_", line[:1])
+}
+
+func heading_level(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level += 1
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ') {
+		return 0
+	}
+	return level
+}
+
+// list_item splits "  * item" into "  ", "-" and "item", the markers of numbered lists stay.
+func list_item(line string) (string, string, string, bool) {
+	rest := strings.TrimLeft(line, " \t")
+	indent := line[:len(line)-len(rest)]
+	marker := ""
+	switch {
+	case strings.HasPrefix(rest, "- "), strings.HasPrefix(rest, "* "), strings.HasPrefix(rest, "+ "):
+		marker = "-"
+	default:
+		digits := 0
+		for digits < len(rest) && rest[digits] >= '0' && rest[digits] <= '9' {
+			digits += 1
+		}
+		if digits == 0 || digits+1 >= len(rest) || (rest[digits] != '.' && rest[digits] != ')') || rest[digits+1] != ' ' {
+			return "", "", "", false
+		}
+		marker = rest[:digits+1]
+	}
+	return indent, marker, strings.TrimSpace(rest[len(marker):]), true
+}
+
+// with_style adds style to all of a line of size bytes, the parts without spans get one.
+func with_style(spans []StyleSpan, size int, style TextStyle) []StyleSpan {
+	var result []StyleSpan
+	pos := 0
+	for _, span := range spans {
+		if span.Start > pos {
+			result = append(result, StyleSpan{Start: pos, End: span.Start, Style: style})
+		}
+		result = append(result, StyleSpan{Start: span.Start, End: span.End, Style: span.Style | style})
+		pos = span.End
+	}
+	if pos < size {
+		result = append(result, StyleSpan{Start: pos, End: size, Style: style})
+	}
+	return result
+}
+
+type delimiter struct {
+	pos       int // in the line
+	size      int
+	char      byte
+	can_open  bool
+	can_close bool
+	match     int // the delimiter that closes (or opens) this one, -1 if none does
+}
+
+// find_delimiters returns the runs of '*', '_' and '`' in line, the ones that pair up have a match.
+func find_delimiters(line string) []delimiter {
+	var result []delimiter
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' {
+			i += 1
+			continue
+		}
+		if c != '*' && c != '_' && c != '`' {
+			continue
+		}
+		size := 1
+		for i+size < len(line) && line[i+size] == c {
+			size += 1
+		}
+		before, _ := utf8.DecodeLastRuneInString(line[:i])
+		after, _ := utf8.DecodeRuneInString(line[i+size:])
+		if i == 0 {
+			before = ' '
+		}
+		if i+size == len(line) {
+			after = ' '
+		}
+		d := delimiter{pos: i, size: size, char: c, match: -1}
+		d.can_open = !unicode.IsSpace(after)
+		d.can_close = !unicode.IsSpace(before)
+		if c == '_' {
+			// NOTE: snake_case isn't emphasis
+			d.can_open = d.can_open && !unicode.IsLetter(before) && !unicode.IsDigit(before)
+			d.can_close = d.can_close && !unicode.IsLetter(after) && !unicode.IsDigit(after)
+		}
+		if c == '`' {
+			d.can_open, d.can_close = true, true
+		}
+		result = append(result, d)
+		i += size - 1
+	}
+
+	// pair them, the closer goes with the last opener that has the same char and size,
+	// what was opened after that stays as it is
+	var stack []int
+	for i := range result {
+		d := &result[i]
+		if d.can_close {
+			found := -1
+			for j := len(stack) - 1; j >= 0; j-- {
+				o := result[stack[j]]
+				if o.char == d.char && o.size == d.size {
+					found = j
+					break
+				}
+				if o.char == '`' {
+					break // NOTE: nothing closes across code
+				}
+			}
+			if found >= 0 {
+				d.match = stack[found]
+				result[stack[found]].match = i
+				stack = stack[:found]
+				continue
+			}
+		}
+		if d.can_open && (len(stack) == 0 || result[stack[len(stack)-1]].char != '`') {
+			stack = append(stack, i)
+		}
+	}
+	return result
+}
+
+// parse_emphasis takes the emphasis and code markup out of line.
+func parse_emphasis(line string) (string, []StyleSpan) {
+	delimiters := find_delimiters(line)
+	var buf strings.Builder
+	var spans []StyleSpan
+	style := TextStyle(0)
+	start := 0 // where the current style started in buf
+	set_style := func(next TextStyle) {
+		if next == style {
+			return
+		}
+		if style != 0 && buf.Len() > start {
+			spans = append(spans, StyleSpan{Start: start, End: buf.Len(), Style: style})
+		}
+		style = next
+		start = buf.Len()
+	}
+
+	next := 0 // the next delimiter
+	in_code := -1
+	for i := 0; i < len(line); i++ {
+		if next < len(delimiters) && delimiters[next].pos == i {
+			d := delimiters[next]
+			next += 1
+			switch {
+			case d.match < 0 || (in_code >= 0 && d.match != in_code):
+				buf.WriteString(line[i : i+d.size])
+			case d.char == '`':
+				if in_code < 0 {
+					in_code = next - 1
+				} else {
+					in_code = -1
+				}
+			default:
+				toggle := TextStyle(0)
+				if d.size >= 2 {
+					toggle |= STYLE_BOLD
+				}
+				if d.size%2 == 1 {
+					toggle |= STYLE_ITALIC
+				}
+				set_style(style ^ toggle)
+			}
+			i += d.size - 1
+			continue
+		}
+		if line[i] == '\\' && in_code < 0 && i+1 < len(line) && strings.IndexByte("\\`*_#>-+[]()!", line[i+1]) >= 0 {
+			i += 1
+		}
+		buf.WriteByte(line[i])
+	}
+	set_style(0)
+	return buf.String(), spans
+}
+
+// StyledRuns cuts the part of a line that starts at offset and is text long into runs,
+// nil if none of it is styled.
+func StyledRuns(text string, offset int, spans []StyleSpan) []StyledRun {
+	end := offset + len(text)
+	var result []StyledRun
+	styled := false
+	pos := offset
+	for _, span := range spans {
+		if span.End <= pos || span.Start >= end {
+			continue
+		}
+		if span.Start > pos {
+			result = append(result, StyledRun{Text: text[pos-offset : span.Start-offset]})
+			pos = span.Start
+		}
+		stop := min_int(span.End, end)
+		result = append(result, StyledRun{Text: text[pos-offset : stop-offset], Style: span.Style})
+		styled = styled || span.Style != 0
+		pos = stop
+	}
+	if !styled {
+		return nil
+	}
+	if pos < end {
+		result = append(result, StyledRun{Text: text[pos-offset:]})
+	}
+	return result
+}
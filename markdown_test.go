@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMarkdownLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+		spans    []StyleSpan
+	}{
+		{"plain text", "plain text", nil},
+		{"un **mot** gras", "un mot gras", []StyleSpan{{3, 6, STYLE_BOLD}}},
+		{"un *mot* et _un autre_", "un mot et un autre", []StyleSpan{{3, 6, STYLE_ITALIC}, {10, 18, STYLE_ITALIC}}},
+		{"***tout*** __gras__", "tout gras", []StyleSpan{{0, 4, STYLE_BOLD | STYLE_ITALIC}, {5, 9, STYLE_BOLD}}},
+		{"**gras *et italique* gras**", "gras et italique gras", []StyleSpan{{0, 5, STYLE_BOLD}, {5, 16, STYLE_BOLD | STYLE_ITALIC}, {16, 21, STYLE_BOLD}}},
+		{"snake_case_name and 2 * 3 * 4", "snake_case_name and 2 * 3 * 4", nil},
+		{"pas *fermé", "pas *fermé", nil},
+		{"\\*pas\\* en italique", "*pas* en italique", nil},
+		{"le code `a *b* c` reste", "le code a *b* c reste", nil},
+		{"## Chapitre *un* ##", "Chapitre un", []StyleSpan{{0, 9, STYLE_BOLD | STYLE_HEADING}, {9, 11, STYLE_BOLD | STYLE_ITALIC | STYLE_HEADING}}},
+		{"#hashtag", "#hashtag", nil},
+		{"> une **citation**", "une citation", []StyleSpan{{0, 4, STYLE_ITALIC | STYLE_QUOTE}, {4, 12, STYLE_BOLD | STYLE_ITALIC | STYLE_QUOTE}}},
+		{"  * un *point*", "  - un point", []StyleSpan{{7, 12, STYLE_ITALIC}}},
+		{"12. douze", "12. douze", nil},
+		{"1.5 n'est pas une liste", "1.5 n'est pas une liste", nil},
+		{"* * *", "", nil},
+	}
+	for _, test := range tests {
+		plain, spans := ParseMarkdownLine(test.line)
+		if plain != test.expected || !reflect.DeepEqual(spans, test.spans) {
+			t.Errorf("ParseMarkdownLine(%q) failed: got (%q, %v) expected (%q, %v)", test.line, plain, spans, test.expected, test.spans)
+		}
+	}
+}
+
+func TestParseMarkdown(t *testing.T) {
+	text := "# Titre\r\n\r\ndu *texte*\r\n```\nx = a * b * c\n```\nfin"
+	lines, spans := ParseMarkdown(text)
+	expected := []string{"Titre", "", "du texte", "x = a * b * c", "fin"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("ParseMarkdown failed: got (%q) expected (%q)", lines, expected)
+	}
+	if len(spans) != len(lines) || spans[0] == nil || spans[3] != nil {
+		t.Errorf("ParseMarkdown spans failed: got (%v)", spans)
+	}
+}
+
+func TestStyledRuns(t *testing.T) {
+	spans := []StyleSpan{{3, 6, STYLE_BOLD}, {10, 18, STYLE_ITALIC}}
+	line := "un mot et un autre"
+	tests := []struct {
+		offset   int
+		end      int
+		expected []StyledRun
+	}{
+		{0, 10, []StyledRun{{"un ", 0}, {"mot", STYLE_BOLD}, {" et ", 0}}},
+		{10, 18, []StyledRun{{"un autre", STYLE_ITALIC}}},
+		{4, 13, []StyledRun{{"ot", STYLE_BOLD}, {" et ", 0}, {"un ", STYLE_ITALIC}}},
+		{6, 10, nil},
+	}
+	for _, test := range tests {
+		if got := StyledRuns(line[test.offset:test.end], test.offset, spans); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("StyledRuns(%d, %d) failed: got (%v) expected (%v)", test.offset, test.end, got, test.expected)
+		}
+	}
+}
+
+func TestStyleFontName(t *testing.T) {
+	tests := []struct {
+		name     string
+		style    TextStyle
+		expected string
+	}{
+		{"Inconsolata-Regular.ttf", STYLE_BOLD, "Inconsolata-Bold.ttf"},
+		{"Inconsolata-Regular.ttf", STYLE_ITALIC | STYLE_QUOTE, "Inconsolata-Italic.ttf"},
+		{"AnonymousPro-Regular.ttf", STYLE_BOLD | STYLE_ITALIC, "AnonymousPro-BoldItalic.ttf"},
+		{"AnonymousPro-BoldItalic.ttf", STYLE_BOLD, "AnonymousPro-Bold.ttf"},
+		{"DejaVuSansMono.ttf", STYLE_ITALIC, "DejaVuSansMono-Italic.ttf"},
+		{"DejaVuSansMono.ttf", STYLE_HEADING, "DejaVuSansMono.ttf"},
+	}
+	for _, test := range tests {
+		if got := style_font_name(test.name, test.style); got != test.expected {
+			t.Errorf("style_font_name(%q, %d) failed: got (%q) expected (%q)", test.name, test.style, got, test.expected)
+		}
+	}
+}
+
+func TestOpenDocumentMarkdown(t *testing.T) {
+	path := t.TempDir() + "/notes.md"
+	os.WriteFile(path, []byte("# Le **titre**\n\nUne phrase avec un mot en *italique* qui est assez longue pour aller à la ligne."), FILE_MODE_RW)
+	doc, err := OpenDocument(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if got := strings.Join(doc.Lines(0, 3), "|"); !strings.HasPrefix(got, "Le titre||Une phrase") {
+		t.Errorf("OpenDocument failed: got (%q)", got)
+	}
+
+	tw := NewTextWindow(doc, 200, 10)
+	linemeta := make([]LineMetaData, len(tw.lines))
+	tw.SetLineData(linemeta)
+	styled := ""
+	for i := range linemeta {
+		for _, run := range linemeta[i].runs {
+			if run.Style&STYLE_ITALIC != 0 {
+				styled += run.Text
+			}
+		}
+		if linemeta[i].runs != nil && i > 0 && !strings.Contains(tw.lines[i], "italique") {
+			t.Errorf("SetLineData failed: line %d (%q) shouldn't have runs", i, tw.lines[i])
+		}
+	}
+	if styled != "italique" || linemeta[0].runs == nil {
+		t.Errorf("SetLineData failed: got (%q) in italics expected (%q)", styled, "italique")
+	}
+
+	if text, err := LoadText("", path); err != nil || strings.Contains(text, "*") || strings.Contains(text, "#") {
+		t.Errorf("LoadText failed: got (%q, %v)", text, err)
+	}
+}
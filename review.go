@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// SM-2 (https://super-memory.com/english/ol/sm2.htm) with the four answer buttons
+// we know from Anki instead of SM-2's 0-5 quality scale.
+
+type Grade uint8
+
+const (
+	GRADE_AGAIN Grade = iota + 1 // same as the keys 1-4 in the review mode
+	GRADE_HARD
+	GRADE_GOOD
+	GRADE_EASY
+)
+
+const (
+	SM2_INITIAL_EASE = 2.5
+	SM2_MIN_EASE     = 1.3
+)
+
+func (g Grade) String() string {
+	switch g {
+	case GRADE_AGAIN:
+		return "again"
+	case GRADE_HARD:
+		return "hard"
+	case GRADE_GOOD:
+		return "good"
+	case GRADE_EASY:
+		return "easy"
+	}
+	return "unknown"
+}
+
+// sm2_quality maps a grade to SM-2's q, anything below 3 is a failed answer
+func (g Grade) sm2_quality() float64 {
+	switch g {
+	case GRADE_HARD:
+		return 3
+	case GRADE_GOOD:
+		return 4
+	case GRADE_EASY:
+		return 5
+	}
+	return 1
+}
+
+type ReviewEntry struct {
+	Time     time.Time `json:"time"`
+	Grade    Grade     `json:"grade"`
+	Interval int       `json:"interval"` // in days, the one that this answer gave us
+}
+
+type ReviewData struct {
+	Ease        float64       `json:"ease"`
+	Interval    int           `json:"interval"` // in days
+	Repetitions int           `json:"reps"`     // correct answers in a row
+	Due         time.Time     `json:"due"`
+	History     []ReviewEntry `json:"history,omitempty"`
+}
+
+// NewReviewData is due right away, new words should be seen the day they're marked.
+func NewReviewData(now time.Time) *ReviewData {
+	return &ReviewData{Ease: SM2_INITIAL_EASE, Due: start_of_day(now)}
+}
+
+func (r *ReviewData) IsDue(now time.Time) bool {
+	return r.Due.Before(start_of_day(now).AddDate(0, 0, 1))
+}
+
+// NextReview only depends on its arguments, so the same answer at the same time
+// always gives the same schedule.
+func NextReview(r ReviewData, g Grade, now time.Time) ReviewData {
+	q := g.sm2_quality()
+	if q < 3 {
+		r.Repetitions = 0
+		r.Interval = 1
+	} else {
+		switch r.Repetitions {
+		case 0:
+			r.Interval = 1
+		case 1:
+			r.Interval = 6
+		default:
+			r.Interval = int(math.Round(float64(r.Interval) * r.Ease))
+		}
+		r.Repetitions += 1
+	}
+
+	r.Ease += 0.1 - (5-q)*(0.08+(5-q)*0.02)
+	if r.Ease < SM2_MIN_EASE {
+		r.Ease = SM2_MIN_EASE
+	}
+	r.Due = start_of_day(now).AddDate(0, 0, r.Interval)
+
+	// NOTE: copy so we never append into the caller's backing array
+	r.History = append(append([]ReviewEntry(nil), r.History...), ReviewEntry{Time: now, Grade: g, Interval: r.Interval})
+	return r
+}
+
+// SetStatus is the one place where words enter (or stay in) the review schedule.
+func (rec *WordRecord) SetStatus(status WordStatus, now time.Time) {
+	rec.Status = status
+	rec.LastSeen = now
+	if status == WORD_STATUS_LEARNING && rec.Review == nil {
+		rec.Review = NewReviewData(now)
+	}
+}
+
+func start_of_day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+type Clock interface {
+	Now() time.Time
+}
+
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+type DueWord struct {
+	Word   string
+	Record WordRecord
+}
+
+type Scheduler struct {
+	db    *bolt.DB
+	lang  string
+	clock Clock
+}
+
+func NewScheduler(db *bolt.DB, lang string, clock Clock) *Scheduler {
+	return &Scheduler{db: db, lang: lang, clock: clock}
+}
+
+// DueQueue returns every learning word that is due today, the most overdue first.
+func (s *Scheduler) DueQueue() ([]DueWord, error) {
+	var result []DueWord
+	now := s.clock.Now()
+	err := DBForEachWord(s.db, s.lang, func(word string, rec WordRecord) error {
+		if rec.Status != WORD_STATUS_LEARNING {
+			return nil
+		}
+		if rec.Review == nil { // marked as learning before we had a scheduler
+			rec.Review = NewReviewData(now)
+		}
+		if rec.Review.IsDue(now) {
+			result = append(result, DueWord{Word: word, Record: rec})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Record.Review.Due.Before(result[j].Record.Review.Due)
+	})
+	return result, nil
+}
+
+// Answer records a graded answer for word and stores its next due date.
+func (s *Scheduler) Answer(word string, g Grade) (ReviewData, error) {
+	var result ReviewData
+	if g < GRADE_AGAIN || g > GRADE_EASY {
+		return result, fmt.Errorf("bad grade '%d' for '%s'", g, word)
+	}
+	now := s.clock.Now()
+	err := DBUpdateWord(s.db, s.lang, word, func(rec *WordRecord) error {
+		if rec.Review == nil {
+			rec.Review = NewReviewData(now)
+		}
+		result = NextReview(*rec.Review, g, now)
+		rec.Review = &result
+		rec.LastSeen = now
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Full-screen flashcards for the words that are due today.
+// space/return shows the back of the card, 1-4 grades it, escape leaves.
+
+type ReviewMode struct {
+	show     bool
+	revealed bool
+	sched    *Scheduler
+	queue    []DueWord
+	current  int
+	font     *ttf.Font
+	font_w   int
+	card     Popup
+	text     MultiLine
+}
+
+func NewReviewMode(renderer *sdl.Renderer, font *ttf.Font) ReviewMode {
+	rm := ReviewMode{font: font}
+	rm.font_w, _, _ = font.SizeUTF8(" ")
+	rm.text.New(renderer, font)
+	return rm
+}
+
+func (rm *ReviewMode) Start(renderer *sdl.Renderer, db *bolt.DB, lang string) error {
+	if db == nil {
+		return NoDatabaseError
+	}
+	rm.sched = NewScheduler(db, lang, SystemClock{})
+	queue, err := rm.sched.DueQueue()
+	if err != nil {
+		return err
+	}
+	rm.queue = queue
+	rm.current = 0
+	rm.revealed = false
+	rm.show = true
+	rm.Redraw(renderer)
+	return nil
+}
+
+func (rm *ReviewMode) Stop() {
+	rm.show = false
+	rm.queue = nil
+}
+
+func (rm *ReviewMode) HandleKey(renderer *sdl.Renderer, key sdl.Keycode) error {
+	switch key {
+	case sdl.K_ESCAPE:
+		rm.Stop()
+	case sdl.K_SPACE, sdl.K_RETURN:
+		if !rm.revealed && rm.current < len(rm.queue) {
+			rm.revealed = true
+			rm.Redraw(renderer)
+		}
+	case sdl.K_1, sdl.K_2, sdl.K_3, sdl.K_4:
+		if !rm.revealed || rm.current >= len(rm.queue) {
+			break
+		}
+		grade := GRADE_AGAIN + Grade(key-sdl.K_1)
+		if _, err := rm.sched.Answer(rm.queue[rm.current].Word, grade); err != nil {
+			return err
+		}
+		rm.current += 1
+		rm.revealed = false
+		rm.Redraw(renderer)
+	}
+	return nil
+}
+
+func (rm *ReviewMode) card_lines() []string {
+	if rm.current >= len(rm.queue) {
+		return []string{"Nothing left to review today.", " ", "[esc] back to reading"}
+	}
+	due := rm.queue[rm.current]
+	word := due.Word
+	if ctx := due.Record.Context; ctx != nil && ctx.Form != "" {
+		word += " (" + ctx.Form + ")" // the lemma, and the form we met it in
+	}
+	lines := []string{
+		fmt.Sprintf("%d/%d", rm.current+1, len(rm.queue)),
+		" ",
+		word,
+		" ",
+	}
+	if !rm.revealed {
+		return append(lines, "[space] show answer")
+	}
+	note := due.Record.Note
+	if note == "" {
+		note = "(no note)"
+	}
+	lines = append(lines, note, " ")
+	if ctx := due.Record.Context; ctx != nil {
+		lines = append(lines, "\""+ctx.Sentence+"\"", "  - "+ctx.File, " ")
+	}
+	if r := due.Record.Review; r != nil && len(r.History) > 0 {
+		lines = append(lines, fmt.Sprintf("every %d days, ease %.2f", r.Interval, r.Ease), " ")
+	}
+	return append(lines, "[1] again [2] hard [3] good [4] easy")
+}
+
+func (rm *ReviewMode) Redraw(renderer *sdl.Renderer) {
+	var lines []string
+	for _, line := range rm.card_lines() {
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, " ") // NOTE: RenderUTF8Blended fails on ""
+			continue
+		}
+		lines = append(lines, NewWrapLines(line, int(rm.text.bg_rect.W), rm.font_w)...)
+	}
+	rm.text.ClearAndWrite(renderer, rm.font, lines, rm.text.lineskip, 0)
+}
+
+func (rm *ReviewMode) Draw(renderer *sdl.Renderer) {
+	w, h, _ := renderer.GetOutputSize()
+	draw_rect_without_border(renderer, &sdl.Rect{X: 0, Y: 0, W: w, H: h}, &COLOR_PICKLED_BLUEWOOD)
+
+	pad := int32(10)
+	rm.text.bg_rect.X = (w - rm.text.bg_rect.W) / 2
+	rm.text.bg_rect.Y = (h - rm.text.bg_rect.H) / 2
+
+	// the arrow on the right says "there is a next card"
+	rm.card = NewPopup(rm.text.bg_rect.X+rm.text.bg_rect.W+pad*2, rm.text.bg_rect.Y+rm.text.bg_rect.H/2-pad, pad, byte('R'))
+	rm.card.rect = sdl.Rect{
+		X: rm.text.bg_rect.X - pad,
+		Y: rm.text.bg_rect.Y - pad,
+		W: rm.text.bg_rect.W + pad*2,
+		H: rm.text.bg_rect.H + pad*2,
+	}
+	rm.card.Draw(renderer)
+	renderer.Copy(rm.text.texture, nil, &rm.text.bg_rect)
+}
+
+func (rm *ReviewMode) Destroy() {
+	rm.text.texture.Destroy()
+	rm.text.fmt.Free()
+	rm.font.Close()
+}
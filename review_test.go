@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(days int) {
+	c.now = c.now.AddDate(0, 0, days)
+}
+
+var review_start = time.Date(2022, 11, 5, 18, 30, 0, 0, time.UTC)
+
+func TestNextReview(t *testing.T) {
+	test := []struct {
+		grade    Grade
+		interval int
+		reps     int
+		ease     float64
+	}{
+		{grade: GRADE_GOOD, interval: 1, reps: 1, ease: 2.5},
+		{grade: GRADE_GOOD, interval: 6, reps: 2, ease: 2.5},
+		{grade: GRADE_EASY, interval: 15, reps: 3, ease: 2.6},
+		{grade: GRADE_HARD, interval: 39, reps: 4, ease: 2.46},
+		{grade: GRADE_AGAIN, interval: 1, reps: 0, ease: 1.92},
+		{grade: GRADE_GOOD, interval: 1, reps: 1, ease: 1.92},
+	}
+	r := *NewReviewData(review_start)
+	now := review_start
+	for i, tt := range test {
+		r = NextReview(r, tt.grade, now)
+		if r.Interval != tt.interval || r.Repetitions != tt.reps || !almost_equal(r.Ease, tt.ease) {
+			t.Errorf("TestNextReview failed (%d, %s): got (%d, %d, %.2f) expected (%d, %d, %.2f)",
+				i, tt.grade, r.Interval, r.Repetitions, r.Ease, tt.interval, tt.reps, tt.ease)
+		}
+		if want := start_of_day(now).AddDate(0, 0, tt.interval); !r.Due.Equal(want) {
+			t.Errorf("TestNextReview failed (%d): got due (%v) expected (%v)", i, r.Due, want)
+		}
+		now = r.Due.Add(9 * time.Hour)
+	}
+	if len(r.History) != len(test) {
+		t.Errorf("TestNextReview failed: got (%d) history entries expected (%d)", len(r.History), len(test))
+	}
+}
+
+func TestNextReviewMinEase(t *testing.T) {
+	r := *NewReviewData(review_start)
+	for i := 0; i < 20; i++ {
+		r = NextReview(r, GRADE_AGAIN, review_start)
+	}
+	if r.Ease != SM2_MIN_EASE {
+		t.Errorf("TestNextReviewMinEase failed: got (%.2f) expected (%.2f)", r.Ease, SM2_MIN_EASE)
+	}
+}
+
+func TestSchedulerDueQueue(t *testing.T) {
+	db := openTestDB(t)
+	clock := &fakeClock{now: review_start}
+	sched := NewScheduler(db, "fr", clock)
+
+	DBInit(db, "fr", map[string]WordContext{"maison": {}, "chat": {}, "chien": {}})
+	for _, word := range []string{"maison", "chat"} {
+		DBUpdateWord(db, "fr", word, func(rec *WordRecord) error {
+			rec.SetStatus(WORD_STATUS_LEARNING, clock.Now())
+			return nil
+		})
+	}
+
+	due, err := sched.DueQueue()
+	if err != nil || len(due) != 2 {
+		t.Fatalf("DueQueue failed: got (%v, %v) expected 2 words", due, err)
+	}
+
+	if _, err := sched.Answer("maison", GRADE_GOOD); err != nil {
+		t.Fatalf("Answer failed: %v", err)
+	}
+	if _, err := sched.Answer("chat", GRADE_EASY); err != nil {
+		t.Fatalf("Answer failed: %v", err)
+	}
+	if due, _ = sched.DueQueue(); len(due) != 0 {
+		t.Errorf("DueQueue failed: got (%d) words expected none right after answering", len(due))
+	}
+
+	clock.Advance(1)
+	if due, _ = sched.DueQueue(); len(due) != 2 {
+		t.Errorf("DueQueue failed the next day: got (%v)", due)
+	}
+
+	if _, err := sched.Answer("maison", Grade(9)); err == nil {
+		t.Errorf("Answer should fail on a bad grade")
+	}
+}
+
+func almost_equal(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
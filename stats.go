@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+
+	"tester/tokenizer"
+)
+
+// How much of a text we already know, counted two ways:
+// unique words (what GetUniqueWords finds) and running tokens (every occurrence of them).
+// 95%+ known tokens is comfortable reading, much lower and the text is probably too hard for now.
+
+type TextStats struct {
+	File   string
+	Lang   string
+	Unique [WORD_STATUS_IGNORED + 1]int // indexed by WordStatus
+	Tokens [WORD_STATUS_IGNORED + 1]int
+}
+
+func (ts *TextStats) TotalUnique() int {
+	return sum_counts(ts.Unique[:])
+}
+
+func (ts *TextStats) TotalTokens() int {
+	return sum_counts(ts.Tokens[:])
+}
+
+func (ts *TextStats) UniqueShare(status WordStatus) float64 {
+	return share(ts.Unique[status], ts.TotalUnique())
+}
+
+func (ts *TextStats) TokenShare(status WordStatus) float64 {
+	return share(ts.Tokens[status], ts.TotalTokens())
+}
+
+func sum_counts(counts []int) int {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}
+
+func share(n int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+// ComputeTextStats counts words missing from the db as WORD_STATUS_NEW, db may be nil.
+func ComputeTextStats(db *bolt.DB, lang string, filename string, text string) (TextStats, error) {
+	ts := TextStats{File: filename, Lang: lang}
+	counts := make(map[string]int)
+	var words []string
+	for _, line := range strings.Split(text, "\n") {
+		for _, tok := range LineWords(line) {
+			key := WordKey(tok.Text)
+			if counts[key] == 0 {
+				words = append(words, key)
+			}
+			counts[key] += 1
+		}
+	}
+
+	statuses := map[string]WordStatus{}
+	if db != nil {
+		var err error
+		if statuses, err = DBGetStatuses(db, lang, words); err != nil {
+			return ts, err
+		}
+	}
+	for _, word := range words {
+		status := statuses[word] // NOTE: missing is WORD_STATUS_NEW
+		ts.Unique[status] += 1
+		ts.Tokens[status] += counts[word]
+	}
+	return ts, nil
+}
+
+// Lines is what both the stats panel and 'tester stats' print.
+func (ts *TextStats) Lines() []string {
+	lines := []string{
+		fmt.Sprintf("%s [%s]: %d unique words, %d tokens", ts.File, ts.Lang, ts.TotalUnique(), ts.TotalTokens()),
+	}
+	for _, status := range []WordStatus{WORD_STATUS_KNOWN, WORD_STATUS_LEARNING, WORD_STATUS_NEW, WORD_STATUS_IGNORED} {
+		if status == WORD_STATUS_IGNORED && ts.Unique[status] == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  %-8s %5.1f%% unique  %5.1f%% tokens",
+			status.String(), ts.UniqueShare(status), ts.TokenShare(status)))
+	}
+	return lines
+}
+
+// ComputeDirStats computes TextStats for every file in dir, flag_lang works like it does for TextLanguage.
+// Every file is counted with the lemmas and rules of its own language, WORD_LEMMATIZER and TEXT_RULES
+// are put back afterwards, the open text goes on with its own.
+func ComputeDirStats(db *bolt.DB, config AppConfig, dir string, files []string, flag_lang string) ([]TextStats, error) {
+	defer func(lemmatizer Lemmatizer, rules *tokenizer.Rules) {
+		WORD_LEMMATIZER = lemmatizer
+		TEXT_RULES = rules
+	}(WORD_LEMMATIZER, TEXT_RULES)
+
+	var result []TextStats
+	for _, name := range files {
+		data, err := LoadText(dir, name)
+		if err != nil {
+			return result, err
+		}
+		lang := TextLanguage(dir, name, flag_lang)
+		if err = UseLanguage(config, lang); err != nil {
+			return result, err
+		}
+		ts, err := ComputeTextStats(db, lang, name, data)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, ts)
+	}
+	return result, nil
+}
+
+// tester stats [-lang fr] [file ...], without files it goes through every text in ./text/ (see TEXT_EXTENSIONS)
+func RunStatsCommand(db *bolt.DB, config AppConfig, args []string, stdout io.Writer) error {
+	set := flag.NewFlagSet("stats", flag.ContinueOnError)
+	lang := set.String("lang", "", "vocabulary language, overrides the text's .cfg")
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+
+	var all []TextStats
+	if set.NArg() == 0 {
+		var files []string
+		for _, ext := range TEXT_EXTENSIONS {
+			found, err := filepath.Glob(filepath.Join(DEFAULT_TEXT_DIR, "*"+ext))
+			if err != nil {
+				return err
+			}
+			for i := range found {
+				files = append(files, filepath.Base(found[i]))
+			}
+		}
+		var err error
+		if all, err = ComputeDirStats(db, config, DEFAULT_TEXT_DIR, files, *lang); err != nil {
+			return err
+		}
+	}
+	for _, path := range set.Args() {
+		dir, name := filepath.Split(path)
+		stats, err := ComputeDirStats(db, config, dir, []string{name}, *lang)
+		if err != nil {
+			return err
+		}
+		all = append(all, stats...)
+	}
+
+	for _, ts := range all {
+		for _, line := range ts.Lines() {
+			fmt.Fprintln(stdout, line)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+	bolt "go.etcd.io/bbolt"
+)
+
+// The "Properties" panel: known-word coverage for every text in text_dir.
+// up/down (page up/page down) scroll through the texts, any other key closes it.
+
+// NOTE: the MultiLine texture is 300px high, this leaves room for the header and the help line
+const STATS_PANEL_ROWS = 11
+
+type StatsPanel struct {
+	show   bool
+	font   *ttf.Font
+	font_w int
+	text   MultiLine
+	lines  []string // the wrapped lines of all the texts
+	first  int      // the first of lines on screen
+}
+
+func NewStatsPanel(renderer *sdl.Renderer, font *ttf.Font) StatsPanel {
+	sp := StatsPanel{font: font}
+	sp.font_w, _, _ = font.SizeUTF8(" ")
+	sp.text.New(renderer, font)
+	return sp
+}
+
+func (sp *StatsPanel) Open(renderer *sdl.Renderer, db *bolt.DB, config AppConfig, text_dir string, files []string, flag_lang string) error {
+	if db == nil {
+		return NoDatabaseError
+	}
+	all, err := ComputeDirStats(db, config, text_dir, files, flag_lang)
+	if err != nil {
+		return err
+	}
+
+	sp.lines = nil
+	for _, ts := range all {
+		for _, line := range ts.Lines() {
+			sp.lines = append(sp.lines, NewWrapLines(line, int(sp.text.bg_rect.W), sp.font_w)...)
+		}
+		sp.lines = append(sp.lines, " ")
+	}
+	if len(all) == 0 {
+		sp.lines = append(sp.lines, NewWrapLines("No texts in "+text_dir, int(sp.text.bg_rect.W), sp.font_w)...)
+	}
+	sp.first = 0
+	sp.show = true
+	sp.Redraw(renderer)
+	return nil
+}
+
+// HandleKey scrolls with up/down and page up/page down, every other key closes the panel.
+func (sp *StatsPanel) HandleKey(renderer *sdl.Renderer, key sdl.Keycode) {
+	first := sp.first
+	switch key {
+	case sdl.K_UP:
+		first -= 1
+	case sdl.K_DOWN:
+		first += 1
+	case sdl.K_PAGEUP:
+		first -= STATS_PANEL_ROWS
+	case sdl.K_PAGEDOWN:
+		first += STATS_PANEL_ROWS
+	default:
+		sp.show = false
+		sp.lines = nil
+		return
+	}
+	if first > len(sp.lines)-STATS_PANEL_ROWS {
+		first = len(sp.lines) - STATS_PANEL_ROWS
+	}
+	if first < 0 {
+		first = 0
+	}
+	if first != sp.first {
+		sp.first = first
+		sp.Redraw(renderer)
+	}
+}
+
+func (sp *StatsPanel) panel_lines() []string {
+	lines := []string{"Known-word coverage", " "}
+	last := min_int(sp.first+STATS_PANEL_ROWS, len(sp.lines))
+	lines = append(lines, sp.lines[sp.first:last]...)
+	help := "[esc] back to reading"
+	if len(sp.lines) > STATS_PANEL_ROWS {
+		help = fmt.Sprintf("%d-%d/%d [up/down] scroll [esc] back", sp.first+1, last, len(sp.lines))
+	}
+	return append(lines, " ", help)
+}
+
+func (sp *StatsPanel) Redraw(renderer *sdl.Renderer) {
+	lines := sp.panel_lines()
+	for i := range lines {
+		if strings.TrimSpace(lines[i]) == "" {
+			lines[i] = " " // NOTE: RenderUTF8Blended fails on ""
+		}
+	}
+	sp.text.ClearAndWrite(renderer, sp.font, lines, sp.text.lineskip, 0)
+}
+
+func (sp *StatsPanel) Draw(renderer *sdl.Renderer) {
+	w, h, _ := renderer.GetOutputSize()
+	draw_rect_without_border(renderer, &sdl.Rect{X: 0, Y: 0, W: w, H: h}, &COLOR_PICKLED_BLUEWOOD)
+	sp.text.bg_rect.X = (w - sp.text.bg_rect.W) / 2
+	sp.text.bg_rect.Y = (h - sp.text.bg_rect.H) / 2
+	renderer.Copy(sp.text.texture, nil, &sp.text.bg_rect)
+}
+
+func (sp *StatsPanel) Destroy() {
+	sp.text.texture.Destroy()
+	sp.text.fmt.Free()
+	sp.font.Close()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tester/tokenizer"
+)
+
+func TestComputeTextStats(t *testing.T) {
+	db := openTestDB(t)
+	for word, status := range map[string]WordStatus{"le": WORD_STATUS_KNOWN, "chat": WORD_STATUS_LEARNING} {
+		if err := DBPutWord(db, "fr", word, WordRecord{Status: status}); err != nil {
+			t.Fatalf("DBPutWord failed: %v", err)
+		}
+	}
+
+	ts, err := ComputeTextStats(db, "fr", "chat.txt", "le chat et le chien\nle chat.")
+	if err != nil {
+		t.Fatalf("ComputeTextStats failed: %v", err)
+	}
+	if ts.TotalUnique() != 4 || ts.TotalTokens() != 7 {
+		t.Errorf("ComputeTextStats failed: got (%d, %d) expected (4, 7)", ts.TotalUnique(), ts.TotalTokens())
+	}
+	expected := [][2]int{
+		WORD_STATUS_KNOWN:    {1, 3},
+		WORD_STATUS_LEARNING: {1, 2},
+		WORD_STATUS_NEW:      {2, 2},
+	}
+	for status, want := range expected {
+		if ts.Unique[status] != want[0] || ts.Tokens[status] != want[1] {
+			t.Errorf("ComputeTextStats(%s) failed: got (%d, %d) expected (%d, %d)",
+				WordStatus(status), ts.Unique[status], ts.Tokens[status], want[0], want[1])
+		}
+	}
+	if share := ts.TokenShare(WORD_STATUS_KNOWN); !almost_equal(share, 300.0/7) {
+		t.Errorf("TokenShare failed: got (%f) expected (%f)", share, 300.0/7)
+	}
+
+	empty, err := ComputeTextStats(nil, "fr", "empty.txt", "")
+	if err != nil || empty.TotalTokens() != 0 || empty.TokenShare(WORD_STATUS_KNOWN) != 0 {
+		t.Errorf("ComputeTextStats failed on an empty text: got (%+v, %v)", empty, err)
+	}
+}
+
+func TestStatsPanelLines(t *testing.T) {
+	sp := StatsPanel{lines: numbered_lines(STATS_PANEL_ROWS * 3)}
+	sp.first = STATS_PANEL_ROWS + 2
+	lines := sp.panel_lines()
+	if len(lines) != STATS_PANEL_ROWS+4 {
+		t.Fatalf("panel_lines failed: got (%d) lines expected (%d)", len(lines), STATS_PANEL_ROWS+4)
+	}
+	if lines[2] != sp.lines[sp.first] || lines[STATS_PANEL_ROWS+1] != sp.lines[sp.first+STATS_PANEL_ROWS-1] {
+		t.Errorf("panel_lines failed: got (%q) expected the lines from %d", lines, sp.first)
+	}
+	expected := fmt.Sprintf("%d-%d/%d [up/down] scroll [esc] back", sp.first+1, sp.first+STATS_PANEL_ROWS, len(sp.lines))
+	if lines[len(lines)-1] != expected {
+		t.Errorf("panel_lines failed: got (%q) expected (%q)", lines[len(lines)-1], expected)
+	}
+
+	sp = StatsPanel{lines: []string{"one", "two"}}
+	if lines := sp.panel_lines(); len(lines) != 6 || lines[len(lines)-1] != "[esc] back to reading" {
+		t.Errorf("panel_lines failed on a short list: got (%q)", lines)
+	}
+}
+
+func TestComputeDirStatsKeepsLanguage(t *testing.T) {
+	db := openTestDB(t)
+	dir := t.TempDir() + "/"
+	config := AppConfig{rules_dir: t.TempDir()}
+	os.WriteFile(filepath.Join(config.rules_dir, "fr"+SETTINGS_EXT), []byte("elisions = l\n"), FILE_MODE_RW)
+	os.WriteFile(filepath.Join(config.rules_dir, "de"+SETTINGS_EXT), []byte("ignore = Berlin\n"), FILE_MODE_RW)
+	for name, lang := range map[string]string{"French.txt": "fr", "German.txt": "de"} {
+		os.WriteFile(dir+name, []byte("l'homme in Berlin"), FILE_MODE_RW)
+		os.WriteFile(dir+name+SETTINGS_EXT, []byte("lang = "+lang+"\n"), FILE_MODE_RW)
+	}
+
+	open := &tokenizer.Rules{Abbreviations: []string{"etc."}}
+	defer func(rules *tokenizer.Rules) { TEXT_RULES = rules }(TEXT_RULES)
+	TEXT_RULES = open
+	if _, err := ComputeDirStats(db, config, dir, []string{"French.txt", "German.txt"}, ""); err != nil {
+		t.Fatalf("ComputeDirStats failed: %v", err)
+	}
+	if TEXT_RULES != open {
+		t.Errorf("ComputeDirStats failed: left TEXT_RULES at (%+v) expected (%+v)", TEXT_RULES, open)
+	}
+}
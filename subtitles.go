@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Subtitles (.srt and .vtt) are read one cue per line of the document, the cue times are kept
+// next to the lines (see TextWindow.SetLineData) and drawn left of the text by TimeGutter.
+// Only the words go into the document, the <i>, {\an8}, <v Bob> and the like are taken out.
+//
+//	1
+//	00:00:01,000 --> 00:00:04,000
+//	<i>Il était une fois...</i>
+
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+func is_subtitles(filename string) bool {
+	ext := strings.ToLower(path.Ext(filename))
+	return ext == ".srt" || ext == ".vtt"
+}
+
+// parse_cue_time reads "01:02:03,456" (SRT) or "01:02:03.456" and "02:03.456" (VTT).
+func parse_cue_time(s string) (time.Duration, error) {
+	s = strings.Replace(s, ",", ".", 1)
+	fields := strings.Split(s, ":")
+	if len(fields) < 2 || len(fields) > 3 {
+		return 0, fmt.Errorf("bad time '%s'", s)
+	}
+	var result time.Duration
+	for i, field := range fields {
+		unit := time.Minute
+		if len(fields) == 3 && i == 0 {
+			unit = time.Hour
+		}
+		if i == len(fields)-1 {
+			seconds, err := strconv.ParseFloat(field, 64)
+			if err != nil || seconds < 0 {
+				return 0, fmt.Errorf("bad time '%s'", s)
+			}
+			result += time.Duration(seconds * float64(time.Second))
+			break
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("bad time '%s'", s)
+		}
+		result += time.Duration(n) * unit
+	}
+	return result.Round(time.Millisecond), nil
+}
+
+// StripCueMarkup returns the words of a cue: tags, {\...} overrides and entities are gone
+// and the lines of the cue are joined into one.
+func StripCueMarkup(text string) string {
+	var buf strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '<':
+			if end := strings.IndexByte(text[i:], '>'); end >= 0 {
+				i += end
+				continue
+			}
+		case text[i] == '{' && i+1 < len(text) && text[i+1] == '\\':
+			if end := strings.IndexByte(text[i:], '}'); end >= 0 {
+				i += end
+				continue
+			}
+		}
+		buf.WriteByte(text[i])
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(buf.String())), " ")
+}
+
+// parse_cues goes through the blocks of text, a block is a cue if it has a "-->" line,
+// what's before that line is the cue number (or id) and what's after is the text.
+// Cues that are left without any words are dropped.
+func parse_cues(text string) ([]Cue, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var cues []Cue
+	for n, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			continue // the header, a NOTE, a STYLE, or junk
+		}
+		start, end, _ := strings.Cut(lines[timing], "-->")
+		fields := strings.Fields(end) // NOTE: VTT has settings after the end time
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("Failed to parse block %d: no end time", n+1)
+		}
+		var cue Cue
+		var err error
+		if cue.Start, err = parse_cue_time(strings.TrimSpace(start)); err != nil {
+			return nil, fmt.Errorf("Failed to parse block %d: '%v'", n+1, err)
+		}
+		if cue.End, err = parse_cue_time(fields[0]); err != nil {
+			return nil, fmt.Errorf("Failed to parse block %d: '%v'", n+1, err)
+		}
+		if cue.Text = StripCueMarkup(strings.Join(lines[timing+1:], "\n")); cue.Text != "" {
+			cues = append(cues, cue)
+		}
+	}
+	return cues, nil
+}
+
+func ParseSRT(text string) ([]Cue, error) {
+	return parse_cues(strings.TrimPrefix(text, "\uFEFF"))
+}
+
+func ParseVTT(text string) ([]Cue, error) {
+	text = strings.TrimPrefix(text, "\uFEFF")
+	if !strings.HasPrefix(text, "WEBVTT") {
+		return nil, fmt.Errorf("not a WebVTT file, it doesn't start with 'WEBVTT'")
+	}
+	return parse_cues(text)
+}
+
+// ParseSubtitles picks the parser by the extension of filename.
+func ParseSubtitles(filename string, text string) ([]Cue, error) {
+	if strings.EqualFold(path.Ext(filename), ".vtt") {
+		return ParseVTT(text)
+	}
+	return ParseSRT(text)
+}
+
+// CueText is the text of the document, one line per cue.
+func CueText(cues []Cue) string {
+	lines := make([]string, len(cues))
+	for i := range cues {
+		lines[i] = cues[i].Text
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CueLabel is what the gutter shows for a cue, "01:02-01:05" or "1:01:02-1:01:05".
+func CueLabel(cue *Cue) string {
+	return format_cue_time(cue.Start) + "-" + format_cue_time(cue.End)
+}
+
+func format_cue_time(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	h, m, s := int(d/time.Hour), int(d/time.Minute)%60, int(d/time.Second)%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseCueTime(t *testing.T) {
+	tests := map[string]time.Duration{
+		"00:00:01,000": time.Second,
+		"01:02:03,456": time.Hour + 2*time.Minute + 3*time.Second + 456*time.Millisecond,
+		"01:02:03.456": time.Hour + 2*time.Minute + 3*time.Second + 456*time.Millisecond,
+		"02:03.5":      2*time.Minute + 3*time.Second + 500*time.Millisecond,
+	}
+	for s, expected := range tests {
+		if got, err := parse_cue_time(s); err != nil || got != expected {
+			t.Errorf("parse_cue_time(%q) failed: got (%v, %v) expected (%v)", s, got, err, expected)
+		}
+	}
+	for _, s := range []string{"", "12", "aa:bb:cc,ddd", "00:-1:00,000", "1:2:3:4"} {
+		if _, err := parse_cue_time(s); err == nil {
+			t.Errorf("parse_cue_time(%q) should fail", s)
+		}
+	}
+}
+
+func TestStripCueMarkup(t *testing.T) {
+	tests := map[string]string{
+		"<i>Il était une fois...</i>":                "Il était une fois...",
+		"{\\an8}Là-haut !":                           "Là-haut !",
+		"- Salut.\n- <b>Bonjour</b> &amp; bienvenue": "- Salut. - Bonjour & bienvenue",
+		"<v Marie>C'est <c.jaune>moi</c></v>":        "C'est moi",
+		"un <00:00:01.500>deux":                      "un deux",
+		"<font color=\"#ffff00\">♪</font>":           "♪",
+		"<i></i>":                                    "",
+	}
+	for text, expected := range tests {
+		if got := StripCueMarkup(text); got != expected {
+			t.Errorf("StripCueMarkup(%q) failed: got (%q) expected (%q)", text, got, expected)
+		}
+	}
+}
+
+func TestParseSRT(t *testing.T) {
+	srt := "\uFEFF1\r\n00:00:01,000 --> 00:00:02,500\r\n<i>Bonjour.</i>\r\n\r\n" +
+		"2\r\n00:00:03,000 --> 00:00:04,000\r\n{\\an8}\r\n\r\n" +
+		"3\r\n00:01:05,000 --> 00:01:07,250\r\nComment ça va ?\r\nTrès bien.\r\n"
+	cues, err := ParseSRT(srt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Cue{
+		{Start: time.Second, End: 2500 * time.Millisecond, Text: "Bonjour."},
+		{Start: time.Minute + 5*time.Second, End: time.Minute + 7250*time.Millisecond, Text: "Comment ça va ? Très bien."},
+	}
+	if len(cues) != len(expected) {
+		t.Fatalf("ParseSRT failed: got (%v) expected (%v)", cues, expected)
+	}
+	for i := range expected {
+		if cues[i] != expected[i] {
+			t.Errorf("ParseSRT cue %d failed: got (%v) expected (%v)", i, cues[i], expected[i])
+		}
+	}
+	if _, err := ParseSRT("1\n00:00:01,000 --> soon\nBonjour."); err == nil {
+		t.Errorf("ParseSRT with a bad end time should fail")
+	}
+}
+
+func TestParseVTT(t *testing.T) {
+	vtt := "WEBVTT - le film\n\nNOTE pas un cue\n\nSTYLE\n::cue { color: yellow }\n\n" +
+		"intro\n00:01.000 --> 00:02.000 align:start line:0\n<v Marie>Bonjour &lt;tout le monde&gt;</v>\n\n" +
+		"01:00:00.000 --> 01:00:03.000\nAu revoir."
+	cues, err := ParseVTT(vtt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Cue{
+		{Start: time.Second, End: 2 * time.Second, Text: "Bonjour <tout le monde>"},
+		{Start: time.Hour, End: time.Hour + 3*time.Second, Text: "Au revoir."},
+	}
+	if len(cues) != len(expected) {
+		t.Fatalf("ParseVTT failed: got (%v) expected (%v)", cues, expected)
+	}
+	for i := range expected {
+		if cues[i] != expected[i] {
+			t.Errorf("ParseVTT cue %d failed: got (%v) expected (%v)", i, cues[i], expected[i])
+		}
+	}
+	if _, err := ParseVTT("1\n00:00:01,000 --> 00:00:02,000\nBonjour."); err == nil {
+		t.Errorf("ParseVTT without the WEBVTT header should fail")
+	}
+}
+
+func TestCueLabel(t *testing.T) {
+	tests := []struct {
+		cue      Cue
+		expected string
+	}{
+		{Cue{Start: 62 * time.Second, End: 65900 * time.Millisecond}, "01:02-01:05"},
+		{Cue{Start: time.Hour + 62*time.Second, End: time.Hour + 65*time.Second}, "1:01:02-1:01:05"},
+	}
+	for _, test := range tests {
+		if got := CueLabel(&test.cue); got != test.expected {
+			t.Errorf("CueLabel failed: got (%q) expected (%q)", got, test.expected)
+		}
+	}
+}
+
+func TestOpenDocumentSubtitles(t *testing.T) {
+	path := t.TempDir() + "/film.srt"
+	// NOTE: old subtitles are often in cp1252
+	srt := "1\n00:00:01,000 --> 00:00:02,000\n<i>D\xe9j\xe0 vu</i>\n\n2\n00:00:03,000 --> 00:00:04,000\nEncore une fois, encore une fois, encore une fois."
+	os.WriteFile(path, []byte(srt), FILE_MODE_RW)
+
+	doc, err := OpenDocument(path, "windows-1252")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if len(doc.cues) != 2 || doc.Lines(0, 1)[0] != "Déjà vu" {
+		t.Fatalf("OpenDocument failed: got (%v)", doc.cues)
+	}
+
+	tw := NewTextWindow(doc, 200, 10)
+	linemeta := make([]LineMetaData, len(tw.lines))
+	tw.SetLineData(linemeta)
+	if linemeta[0].cue != &doc.cues[0] {
+		t.Errorf("SetLineData failed: line 0 got (%v) expected (%v)", linemeta[0].cue, &doc.cues[0])
+	}
+	for i := 1; i < len(linemeta); i++ {
+		if linemeta[i].cue != &doc.cues[1] {
+			t.Errorf("SetLineData failed: line %d got (%v) expected (%v)", i, linemeta[i].cue, &doc.cues[1])
+		}
+	}
+	if len(linemeta) < 3 {
+		t.Errorf("the second cue should wrap, got (%d) lines", len(linemeta))
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/encoding/unicode/utf32"
+)
+
+// Texts are decoded to UTF-8 when they are loaded, everything after that only ever sees UTF-8.
+// A BOM always wins, then the "encoding" text setting (see TextEncoding), then we guess:
+// BOM-less UTF-16, valid UTF-8, and Windows-1252 for whatever is left (old Latin-1 texts).
+
+const (
+	BOM_NONE_OR_UNSUPPORTED uint8 = iota
+	BOM_UTF8
+	BOM_UTF16_BE
+	BOM_UTF16_LE
+	BOM_UTF32_BE
+	BOM_UTF32_LE
+)
+
+var BOM_ENCODINGS = [...]string{
+	BOM_NONE_OR_UNSUPPORTED: "",
+	BOM_UTF8:                "utf-8",
+	BOM_UTF16_BE:            "utf-16be",
+	BOM_UTF16_LE:            "utf-16le",
+	BOM_UTF32_BE:            "utf-32be",
+	BOM_UTF32_LE:            "utf-32le",
+}
+
+// NOTE: the BOM is cut off before decoding, so the decoders are told to ignore it
+var TEXT_ENCODINGS = map[string]encoding.Encoding{
+	"utf-8":        unicode.UTF8,
+	"utf-16be":     unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+	"utf-16le":     unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	"utf-32be":     utf32.UTF32(utf32.BigEndian, utf32.IgnoreBOM),
+	"utf-32le":     utf32.UTF32(utf32.LittleEndian, utf32.IgnoreBOM),
+	"latin1":       charmap.ISO8859_1,
+	"iso-8859-1":   charmap.ISO8859_1,
+	"windows-1252": charmap.Windows1252,
+	"cp1252":       charmap.Windows1252,
+}
+
+// how much of the text the UTF-16 guess looks at
+const SNIFF_SIZE = 4096
+
+// how much of the text has to be valid UTF-8 for it to be UTF-8, a mapped file isn't read any further
+const SNIFF_UTF8_SIZE = 4 << 20
+
+// DetectBOM returns the BOM data starts with and its size in bytes.
+// NOTE: the UTF-32 LE BOM starts with the UTF-16 LE one, so it has to be checked first.
+func DetectBOM(data []byte) (uint8, int) {
+	switch {
+	case len(data) >= 3 && data[0] == 0xEF && data[1] == 0xBB && data[2] == 0xBF:
+		return BOM_UTF8, 3
+	case len(data) >= 4 && data[0] == 0x00 && data[1] == 0x00 && data[2] == 0xFE && data[3] == 0xFF:
+		return BOM_UTF32_BE, 4
+	case len(data) >= 4 && data[0] == 0xFF && data[1] == 0xFE && data[2] == 0x00 && data[3] == 0x00:
+		return BOM_UTF32_LE, 4
+	case len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF:
+		return BOM_UTF16_BE, 2
+	case len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE:
+		return BOM_UTF16_LE, 2
+	default: // No Encoding or Unsupported encodings
+		return BOM_NONE_OR_UNSUPPORTED, 0
+	}
+}
+
+// GetBOM leaves file right after the BOM (or at the start if there is none).
+func GetBOM(bom []byte, file *os.File) uint8 {
+	result, size := DetectBOM(bom)
+	file.Seek(int64(size), io.SeekStart)
+	return result
+}
+
+// sniff_utf16 guesses UTF-16 without a BOM from the zero bytes: mostly-ASCII text has a zero
+// in every other byte, the high one, which is the odd one in LE and the even one in BE.
+func sniff_utf16(data []byte) string {
+	if len(data) > SNIFF_SIZE {
+		data = data[:SNIFF_SIZE]
+	}
+	pairs := len(data) / 2
+	if pairs < 2 {
+		return ""
+	}
+	even, odd := 0, 0
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0 {
+			even += 1
+		}
+		if data[i+1] == 0 {
+			odd += 1
+		}
+	}
+	switch {
+	case odd*10 >= pairs*4 && even*10 < pairs:
+		return "utf-16le"
+	case even*10 >= pairs*4 && odd*10 < pairs:
+		return "utf-16be"
+	}
+	return ""
+}
+
+// SniffEncoding guesses the encoding of data, bom is how many bytes the BOM takes.
+func SniffEncoding(data []byte) (name string, bom int) {
+	if kind, size := DetectBOM(data); kind != BOM_NONE_OR_UNSUPPORTED {
+		return BOM_ENCODINGS[kind], size
+	}
+	if name := sniff_utf16(data); name != "" {
+		return name, 0
+	}
+	if utf8.Valid(utf8_prefix(data, SNIFF_UTF8_SIZE)) {
+		return "utf-8", 0
+	}
+	// NOTE: Windows-1252 is Latin-1 plus letters like œ and € where Latin-1 has control characters nobody uses
+	return "windows-1252", 0
+}
+
+// utf8_prefix is up to n bytes of data, cut before a rune that doesn't fit in them.
+func utf8_prefix(data []byte, n int) []byte {
+	if len(data) <= n {
+		return data
+	}
+	end := n
+	for end > n-utf8.UTFMax && !utf8.RuneStart(data[end]) {
+		end -= 1
+	}
+	return data[:end]
+}
+
+// DecodeText turns data into UTF-8, name is the encoding from the text's settings or "" to guess it.
+func DecodeText(data []byte, name string) (string, error) {
+	guess, bom := SniffEncoding(data)
+	if bom == 0 && name != "" {
+		guess = strings.ToLower(name)
+	}
+	data = data[bom:]
+	if guess == "utf-8" && utf8.Valid(data) {
+		return string(data), nil
+	}
+	enc, ok := TEXT_ENCODINGS[guess]
+	if !ok {
+		return "", fmt.Errorf("unknown text encoding '%s'", guess)
+	}
+	result, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", fmt.Errorf("Failed to decode the text as %s: '%v'", guess, err)
+	}
+	return string(result), nil
+}
+
+// LoadText reads path/filename as UTF-8 whatever it was saved as.
+// For an EPUB it's the text of the book, for subtitles the text of the cues
+// and for Markdown the text without the markup.
+func LoadText(path string, filename string) (string, error) {
+	if is_epub(filename) {
+		book, err := LoadEPUB(path + filename)
+		return book.Text, err
+	}
+	data, err := os.ReadFile(path + filename)
+	if err != nil {
+		return "", err
+	}
+	text, err := DecodeText(data, TextEncoding(path, filename))
+	if err != nil {
+		return "", fmt.Errorf("'%s': %v", filename, err)
+	}
+	if is_subtitles(filename) {
+		cues, err := ParseSubtitles(filename, text)
+		if err != nil {
+			return "", fmt.Errorf("'%s': %v", filename, err)
+		}
+		return CueText(cues), nil
+	}
+	if is_markdown(filename) {
+		lines, _ := ParseMarkdown(text)
+		return strings.Join(lines, "\n"), nil
+	}
+	return text, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+func utf16_bytes(s string, order binary.AppendByteOrder, bom bool) []byte {
+	var result []byte
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	for _, u := range units {
+		result = order.AppendUint16(result, u)
+	}
+	return result
+}
+
+func utf32_bytes(s string, order binary.AppendByteOrder) []byte {
+	var result []byte
+	for _, r := range "\uFEFF" + s {
+		result = order.AppendUint32(result, uint32(r))
+	}
+	return result
+}
+
+func TestDetectBOM(t *testing.T) {
+	test := []struct {
+		input []byte
+		bom   uint8
+		size  int
+	}{
+		{input: []byte{0xEF, 0xBB, 0xBF, 'a'}, bom: BOM_UTF8, size: 3},
+		{input: []byte{0xFE, 0xFF, 0x00, 'a'}, bom: BOM_UTF16_BE, size: 2},
+		{input: []byte{0xFF, 0xFE, 'a', 0x00}, bom: BOM_UTF16_LE, size: 2},
+		{input: []byte{0x00, 0x00, 0xFE, 0xFF}, bom: BOM_UTF32_BE, size: 4},
+		{input: []byte{0xFF, 0xFE, 0x00, 0x00}, bom: BOM_UTF32_LE, size: 4},
+		{input: []byte("abc"), bom: BOM_NONE_OR_UNSUPPORTED, size: 0},
+		{input: []byte{0xFF}, bom: BOM_NONE_OR_UNSUPPORTED, size: 0},
+		{input: nil, bom: BOM_NONE_OR_UNSUPPORTED, size: 0},
+	}
+	for _, tt := range test {
+		if bom, size := DetectBOM(tt.input); bom != tt.bom || size != tt.size {
+			t.Errorf("DetectBOM(%v) failed: got (%d, %d) expected (%d, %d)", tt.input, bom, size, tt.bom, tt.size)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "utf16.txt")
+	os.WriteFile(path, utf16_bytes("hobbit", binary.LittleEndian, true), FILE_MODE_RW)
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	bom := make([]byte, 4)
+	file.Read(bom)
+	if GetBOM(bom, file) != BOM_UTF16_LE {
+		t.Errorf("GetBOM failed: expected BOM_UTF16_LE")
+	}
+	if offset, _ := file.Seek(0, io.SeekCurrent); offset != 2 {
+		t.Errorf("GetBOM failed: seeked to (%d) expected (2)", offset)
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	text := "L'été, la maison.\nŒuvre 中国"
+	test := []struct {
+		name     string
+		input    []byte
+		encoding string
+		expected string
+	}{
+		{name: "utf-8", input: []byte(text), expected: text},
+		{name: "utf-8 bom", input: append([]byte{0xEF, 0xBB, 0xBF}, text...), expected: text},
+		{name: "utf-16le bom", input: utf16_bytes(text, binary.LittleEndian, true), expected: text},
+		{name: "utf-16be bom", input: utf16_bytes(text, binary.BigEndian, true), expected: text},
+		{name: "utf-16le", input: utf16_bytes(text, binary.LittleEndian, false), expected: text},
+		{name: "utf-16be", input: utf16_bytes(text, binary.BigEndian, false), expected: text},
+		{name: "utf-32le bom", input: utf32_bytes(text, binary.LittleEndian), expected: text},
+		{name: "utf-32be bom", input: utf32_bytes(text, binary.BigEndian), expected: text},
+		{name: "latin1 guess", input: []byte("L'\xe9t\xe9, \x9cuvre"), expected: "L'été, œuvre"},
+		{name: "latin1 setting", input: []byte("L'\xe9t\xe9"), encoding: "Latin1", expected: "L'été"},
+		{name: "bom wins", input: utf16_bytes("été", binary.LittleEndian, true), encoding: "windows-1252", expected: "été"},
+		{name: "windows-1252", input: []byte("\x80 5"), encoding: "cp1252", expected: "€ 5"},
+	}
+	for _, tt := range test {
+		result, err := DecodeText(tt.input, tt.encoding)
+		if err != nil || result != tt.expected {
+			t.Errorf("DecodeText(%s) failed: got (%q, %v) expected (%q)", tt.name, result, err, tt.expected)
+		}
+	}
+	if _, err := DecodeText([]byte("abc"), "EBCDIC"); err == nil || err.Error() != "unknown text encoding 'ebcdic'" {
+		t.Errorf("DecodeText with an unknown encoding failed: got (%v) expected (%v)", err, "unknown text encoding 'ebcdic'")
+	}
+}
+
+func TestSniffEncodingPrefix(t *testing.T) {
+	// "é" is cut in half by the end of the sniffed prefix, the latin1 byte is past it
+	data := append(bytes.Repeat([]byte("a"), SNIFF_UTF8_SIZE-1), "é\xe9"...)
+	if name, bom := SniffEncoding(data); name != "utf-8" || bom != 0 {
+		t.Errorf("SniffEncoding failed: got (%s, %d) expected (utf-8, 0)", name, bom)
+	}
+	if got := utf8_prefix(data, SNIFF_UTF8_SIZE); len(got) != SNIFF_UTF8_SIZE-1 {
+		t.Errorf("utf8_prefix failed: got (%d) bytes expected (%d)", len(got), SNIFF_UTF8_SIZE-1)
+	}
+	if name, _ := SniffEncoding([]byte("a\xe9")); name != "windows-1252" {
+		t.Errorf("SniffEncoding failed: got (%s) expected (windows-1252)", name)
+	}
+}
+
+func TestLoadText(t *testing.T) {
+	dir := t.TempDir() + "/"
+	os.WriteFile(dir+"Vieux.txt", []byte("\xc0 la fen\xeatre"), FILE_MODE_RW)
+	os.WriteFile(dir+"Vieux.txt"+SETTINGS_EXT, []byte("lang = fr\nencoding = latin1\n"), FILE_MODE_RW)
+
+	text, err := LoadText(dir, "Vieux.txt")
+	if err != nil || text != "À la fenêtre" {
+		t.Errorf("LoadText failed: got (%q, %v) expected (%q)", text, err, "À la fenêtre")
+	}
+	if _, err := LoadText(dir, "Missing.txt"); err == nil {
+		t.Errorf("LoadText on a missing file should fail")
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+// StyleFonts are the bold, italic and bold italic fonts that go with the text font,
+// e.g. Inconsolata-Bold.ttf for Inconsolata-Regular.ttf. A font without the files for them
+// gets its bold and italic from SDL_ttf instead.
+type StyleFonts struct {
+	fonts [STYLE_FONT_MASK + 1]*ttf.Font // by style & STYLE_FONT_MASK, [0] is the text font itself
+}
+
+// style_font_name is the file of the variant of the font in name for style.
+func style_font_name(name string, style TextStyle) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	// NOTE: -BoldItalic before -Italic, or we'd be left with "-Bold"
+	for _, suffix := range []string{"-Regular", "-BoldItalic", "-Bold", "-Italic"} {
+		if strings.HasSuffix(base, suffix) {
+			base = strings.TrimSuffix(base, suffix)
+			break
+		}
+	}
+	switch style & STYLE_FONT_MASK {
+	case STYLE_BOLD:
+		return base + "-Bold" + ext
+	case STYLE_ITALIC:
+		return base + "-Italic" + ext
+	case STYLE_BOLD | STYLE_ITALIC:
+		return base + "-BoldItalic" + ext
+	}
+	return name
+}
+
+// Load opens the variants of font_dir/name at size, call it whenever the text font changes.
+func (sf *StyleFonts) Load(font_dir string, name string, size int) {
+	sf.Close()
+	for style := STYLE_BOLD; style <= STYLE_FONT_MASK; style++ {
+		variant := style_font_name(name, style)
+		if _, err := os.Stat(font_dir + variant); err == nil {
+			sf.fonts[style] = load_font(font_dir+variant, size)
+			continue
+		}
+		sf.fonts[style] = load_font(font_dir+name, size)
+		ttf_style := 0
+		if style&STYLE_BOLD != 0 {
+			ttf_style |= ttf.STYLE_BOLD
+		}
+		if style&STYLE_ITALIC != 0 {
+			ttf_style |= ttf.STYLE_ITALIC
+		}
+		sf.fonts[style].SetStyle(ttf_style)
+	}
+}
+
+func (sf *StyleFonts) Font(regular *ttf.Font, style TextStyle) *ttf.Font {
+	if f := sf.fonts[style&STYLE_FONT_MASK]; f != nil {
+		return f
+	}
+	return regular
+}
+
+func style_color(style TextStyle, color sdl.Color) sdl.Color {
+	switch {
+	case style&STYLE_HEADING != 0:
+		return COLOR_SAN_MARINER
+	case style&STYLE_QUOTE != 0:
+		return COLOR_PICKLED_BLUEWOOD
+	}
+	return color
+}
+
+// text_width is how wide bytes [start, end) of the line made of runs are when they are drawn.
+func (sf *StyleFonts) text_width(regular *ttf.Font, runs []StyledRun, start int, end int) int {
+	result, pos := 0, 0
+	for _, run := range runs {
+		a, b := start, min_int(end, pos+len(run.Text))
+		if a < pos {
+			a = pos
+		}
+		if a < b {
+			w, _, _ := sf.Font(regular, run.Style).SizeUTF8(run.Text[a-pos : b-pos])
+			result += w
+		}
+		pos += len(run.Text)
+	}
+	return result
+}
+
+// MeasureWords puts the word rects of the styled lines where the words are drawn,
+// populate_line_metadata only knows the width of the text font.
+func (sf *StyleFonts) MeasureWords(regular *ttf.Font, linemeta []LineMetaData) {
+	for i := range linemeta {
+		line := &linemeta[i]
+		if line.runs == nil {
+			continue
+		}
+		var text strings.Builder
+		for _, run := range line.runs {
+			text.WriteString(run.Text)
+		}
+		offset := 0
+		for j, word := range line.words {
+			at := strings.Index(text.String()[offset:], word)
+			if at < 0 {
+				break
+			}
+			offset += at
+			line.word_rects[j].X = int32(X_OFFSET + sf.text_width(regular, line.runs, 0, offset))
+			line.word_rects[j].W = int32(sf.text_width(regular, line.runs, offset, offset+len(word)))
+			offset += len(word)
+		}
+	}
+}
+
+// Render draws runs next to each other, every one with its own font and color.
+func (sf *StyleFonts) Render(regular *ttf.Font, runs []StyledRun, color sdl.Color) (*sdl.Surface, error) {
+	var parts []*sdl.Surface
+	w, h := int32(0), int32(0)
+	for _, run := range runs {
+		if run.Text == "" {
+			continue
+		}
+		part, err := sf.Font(regular, run.Style).RenderUTF8Blended(run.Text, style_color(run.Style, color))
+		if err != nil {
+			continue
+		}
+		parts = append(parts, part)
+		w += part.W
+		if part.H > h {
+			h = part.H
+		}
+	}
+	result, err := sdl.CreateRGBSurfaceWithFormat(0, w, h, 32, uint32(sdl.PIXELFORMAT_ARGB8888))
+	x := int32(0)
+	for _, part := range parts {
+		if err == nil {
+			part.SetBlendMode(sdl.BLENDMODE_NONE) // NOTE: copy the alpha too, the runs don't overlap
+			part.Blit(nil, result, &sdl.Rect{X: x, Y: 0, W: part.W, H: part.H})
+		}
+		x += part.W
+		part.Free()
+	}
+	return result, err
+}
+
+func (sf *StyleFonts) Close() {
+	for i := range sf.fonts {
+		if sf.fonts[i] != nil {
+			sf.fonts[i].Close()
+			sf.fonts[i] = nil
+		}
+	}
+}
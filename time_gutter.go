@@ -0,0 +1,82 @@
+package main
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+// TimeGutter draws the times of the cues on the page between the left edge and the text.
+// The labels are only made again when the page shows other lines.
+
+type TimeGutter struct {
+	font     *ttf.Font
+	font_w   int
+	first    *LineMetaData // the first line of the page the labels were made for
+	textures []*sdl.Texture
+	rects    []sdl.Rect
+}
+
+func NewTimeGutter(font *ttf.Font) TimeGutter {
+	g := TimeGutter{font: font}
+	g.font_w, _, _ = font.SizeUTF8(" ")
+	return g
+}
+
+// Width is how much room the labels of cues need, 0 if there aren't any.
+func (g *TimeGutter) Width(cues []Cue) int {
+	longest := 0
+	for i := range cues {
+		if n := len(CueLabel(&cues[i])); n > longest {
+			longest = n
+		}
+	}
+	if longest == 0 {
+		return 0
+	}
+	return (longest + 1) * g.font_w
+}
+
+func (g *TimeGutter) clear() {
+	for _, texture := range g.textures {
+		texture.Destroy()
+	}
+	g.textures = g.textures[:0]
+	g.rects = g.rects[:0]
+}
+
+// Update makes a label for every line on the page that starts a cue,
+// the line that wraps over from the page before gets one too.
+func (g *TimeGutter) Update(renderer *sdl.Renderer, lines []*LineMetaData, rects []sdl.Rect) {
+	g.clear()
+	g.first = nil
+	if len(lines) == 0 {
+		return
+	}
+	g.first = lines[0]
+	for i, line := range lines {
+		if line.cue == nil || (i > 0 && lines[i-1].cue == line.cue) {
+			continue
+		}
+		texture := MakeTTF_Texture(renderer, g.font, CueLabel(line.cue), &COLOR_PICKLED_BLUEWOOD)
+		_, _, w, h, _ := texture.Query()
+		g.textures = append(g.textures, texture)
+		g.rects = append(g.rects, sdl.Rect{X: int32(X_OFFSET_TEXT), Y: rects[i].Y + (rects[i].H-h)/2, W: w, H: h})
+	}
+}
+
+func (g *TimeGutter) Draw(renderer *sdl.Renderer, lines []*LineMetaData, rects []sdl.Rect) {
+	if len(lines) == 0 || lines[0].cue == nil {
+		return
+	}
+	if lines[0] != g.first {
+		g.Update(renderer, lines, rects)
+	}
+	for i := range g.textures {
+		renderer.Copy(g.textures[i], nil, &g.rects[i])
+	}
+}
+
+func (g *TimeGutter) Destroy() {
+	g.clear()
+	g.font.Close()
+}
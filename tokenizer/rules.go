@@ -0,0 +1,195 @@
+package tokenizer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Rules are what a language changes about tokenizing, they come from a settings file like ./rules/fr.cfg:
+//
+//	word_chars = ·
+//	trim = _
+//	elisions = l d j m n s t c qu jusqu lorsqu puisqu
+//	abbreviations = M. Mme. etc. p.ex.
+//	ignore = Paris Londres
+//	ignore_numbers = true
+type Rules struct {
+	WordChars     string              // besides letters and digits, "·" keeps Catalan "col·legi" together
+	TrimChars     string              // cut off both ends of a word even if they are WordChars
+	Elisions      []string            // "l" splits "l'homme" into an ELISION "l'" and the WORD "homme"
+	Abbreviations []string            // "etc." doesn't end a sentence, see IsAbbreviation
+	Ignore        map[string]struct{} // case folded, these are never vocabulary (names and such)
+	IgnoreNumbers bool                // drop words with digits in them too, like "covid-19"
+}
+
+// DEFAULT_RULES is what we use for a language without a rules file.
+var DEFAULT_RULES = &Rules{Elisions: DEFAULT_ELISIONS}
+
+// ParseRules takes the key/values of a rules file, list values are separated by spaces.
+func ParseRules(settings map[string]string) (*Rules, error) {
+	rules := &Rules{Ignore: make(map[string]struct{})}
+	for key, value := range settings {
+		switch key {
+		case "word_chars":
+			rules.WordChars = strings.Join(strings.Fields(value), "")
+		case "trim":
+			rules.TrimChars = strings.Join(strings.Fields(value), "")
+		case "elisions":
+			for _, e := range strings.Fields(value) {
+				rules.Elisions = append(rules.Elisions, strings.TrimRight(e, APOSTROPHES))
+			}
+		case "abbreviations":
+			rules.Abbreviations = strings.Fields(value)
+		case "ignore":
+			for _, w := range strings.Fields(value) {
+				rules.Ignore[Fold(w)] = struct{}{}
+			}
+		case "ignore_numbers":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("bad ignore_numbers '%s', expected true or false", value)
+			}
+			rules.IgnoreNumbers = b
+		default:
+			return nil, fmt.Errorf("unknown rule '%s'", key)
+		}
+	}
+	sort.Strings(rules.Abbreviations)
+	return rules, nil
+}
+
+func (rules *Rules) is_word_rune(r rune) bool {
+	return is_word_rune(r) || strings.ContainsRune(rules.WordChars, r)
+}
+
+func (rules *Rules) Tokenize(s string) []Token {
+	var result []Token
+	for i := 0; i < len(s); {
+		r, size := decode(s[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case rules.is_word_rune(r):
+			end := rules.scan_word(s, i)
+			result = append(result, rules.word_tokens(s[i:end], i)...)
+			i = end
+		default:
+			end := i + size
+			for end < len(s) {
+				r, size = decode(s[end:])
+				if unicode.IsSpace(r) || rules.is_word_rune(r) {
+					break
+				}
+				end += size
+			}
+			result = append(result, Token{Text: s[i:end], Offset: i, Kind: PUNCT})
+			i = end
+		}
+	}
+	return result
+}
+
+// Words is Tokenize without the numbers, the punctuation, the elisions and the ignored words.
+func (rules *Rules) Words(s string) []Token {
+	var result []Token
+	for _, tok := range rules.Tokenize(s) {
+		if tok.Kind != WORD || rules.ignored(tok.Text) {
+			continue
+		}
+		result = append(result, tok)
+	}
+	return result
+}
+
+func (rules *Rules) ignored(word string) bool {
+	if rules.IgnoreNumbers && strings.IndexFunc(word, unicode.IsDigit) != -1 {
+		return true
+	}
+	_, ok := rules.Ignore[Fold(word)]
+	return ok
+}
+
+// Trim cuts everything that isn't a letter or a digit off both ends of w: "...word!" is "word".
+func (rules *Rules) Trim(w string) string {
+	return strings.Trim(strings.TrimFunc(w, func(r rune) bool { return !rules.is_word_rune(r) }), rules.TrimChars)
+}
+
+// IsAbbreviation is true if word (with its '.') is one of Abbreviations, "(etc." and "Etc." count too.
+func (rules *Rules) IsAbbreviation(word string) bool {
+	word = strings.TrimLeftFunc(word, func(r rune) bool { return !rules.is_word_rune(r) })
+	for _, abbr := range rules.Abbreviations {
+		if strings.EqualFold(word, abbr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rules *Rules) scan_word(s string, start int) int {
+	prev := rune(0)
+	i := start
+	for i < len(s) {
+		r, size := decode(s[i:])
+		if rules.is_word_rune(r) {
+			prev = r
+			i += size
+			continue
+		}
+		next, _ := decode(s[i+size:])
+		if i+size >= len(s) || !rules.joins(prev, r, next) {
+			break
+		}
+		prev = r
+		i += size
+	}
+	return i
+}
+
+func (rules *Rules) joins(prev rune, r rune, next rune) bool {
+	switch {
+	case strings.ContainsRune(APOSTROPHES, r):
+		return IsLetter(next)
+	case strings.ContainsRune(HYPHENS, r):
+		return rules.is_word_rune(next)
+	case strings.ContainsRune(DECIMAL, r):
+		return unicode.IsDigit(prev) && unicode.IsDigit(next)
+	}
+	return false
+}
+
+// word_tokens turns a scanned word into tokens: the TrimChars runes at both ends become PUNCT
+// and an elision at the front becomes its own ELISION token.
+func (rules *Rules) word_tokens(word string, offset int) []Token {
+	var result []Token
+	trimmed := strings.TrimLeft(word, rules.TrimChars)
+	if lead := len(word) - len(trimmed); lead > 0 {
+		result = append(result, Token{Text: word[:lead], Offset: offset, Kind: PUNCT})
+		offset += lead
+	}
+	rest := strings.TrimRight(trimmed, rules.TrimChars)
+	tail := trimmed[len(rest):]
+	trimmed = rest
+	if trimmed != "" {
+		if prefix, rest := SplitElision(trimmed, rules.Elisions); prefix != "" && rest != "" {
+			result = append(result, Token{Text: prefix, Offset: offset, Kind: ELISION})
+			offset += len(prefix)
+			trimmed = rest
+		}
+		result = append(result, Token{Text: trimmed, Offset: offset, Kind: word_kind(trimmed)})
+		offset += len(trimmed)
+	}
+	if tail != "" {
+		result = append(result, Token{Text: tail, Offset: offset, Kind: PUNCT})
+	}
+	return result
+}
+
+func word_kind(word string) Kind {
+	if strings.IndexFunc(word, IsLetter) == -1 {
+		return NUMBER
+	}
+	return WORD
+}
@@ -0,0 +1,86 @@
+package tokenizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(map[string]string{
+		"word_chars":     "· _",
+		"trim":           "_",
+		"elisions":       "l' qu j",
+		"abbreviations":  "M. etc.",
+		"ignore":         "Paris Londres",
+		"ignore_numbers": "true",
+	})
+	if err != nil {
+		t.Fatalf("ParseRules failed: %v", err)
+	}
+	expected := &Rules{
+		WordChars:     "·_",
+		TrimChars:     "_",
+		Elisions:      []string{"l", "qu", "j"},
+		Abbreviations: []string{"M.", "etc."},
+		Ignore:        map[string]struct{}{"paris": {}, "londres": {}},
+		IgnoreNumbers: true,
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("ParseRules failed: got (%+v) expected (%+v)", rules, expected)
+	}
+
+	if _, err := ParseRules(map[string]string{"elision": "l"}); err == nil {
+		t.Errorf("ParseRules should fail on an unknown rule")
+	}
+	if _, err := ParseRules(map[string]string{"ignore_numbers": "sometimes"}); err == nil {
+		t.Errorf("ParseRules should fail on a bad ignore_numbers")
+	}
+}
+
+func TestRulesTokenize(t *testing.T) {
+	rules := &Rules{
+		WordChars:     "·_",
+		TrimChars:     "_",
+		Elisions:      DEFAULT_ELISIONS,
+		Abbreviations: []string{"etc."},
+		Ignore:        map[string]struct{}{"paris": {}},
+		IgnoreNumbers: true,
+	}
+	test := []struct {
+		input    string
+		expected []Token
+	}{
+		{input: "l'homme", expected: []Token{{"l'", 0, ELISION}, {"homme", 2, WORD}}},
+		{input: "Qu’il", expected: []Token{{"Qu’", 0, ELISION}, {"il", 5, WORD}}},
+		{input: "aujourd'hui", expected: []Token{{"aujourd'hui", 0, WORD}}},
+		{input: "col·legi", expected: []Token{{"col·legi", 0, WORD}}},
+		{input: "__init__", expected: []Token{{"__", 0, PUNCT}, {"init", 2, WORD}, {"__", 6, PUNCT}}},
+		{input: "à Paris", expected: []Token{{"à", 0, WORD}, {"Paris", 3, WORD}}},
+	}
+	for _, tt := range test {
+		if result := rules.Tokenize(tt.input); !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("TestRulesTokenize(%q) failed: got (%v) expected (%v)", tt.input, result, tt.expected)
+		}
+	}
+
+	words := rules.Words("l'homme à Paris, covid-19 et 42 chats")
+	expected := []Token{{"homme", 2, WORD}, {"à", 8, WORD}, {"et", 27, WORD}, {"chats", 33, WORD}}
+	if !reflect.DeepEqual(words, expected) {
+		t.Errorf("Rules.Words failed: got (%v) expected (%v)", words, expected)
+	}
+
+	// the ignore list is folded like the word keys are
+	folded, err := ParseRules(map[string]string{"ignore": "Straße ΟΔΥΣΣΕΥΣ"})
+	if err != nil {
+		t.Fatalf("ParseRules failed: %v", err)
+	}
+	if words := folded.Words("STRASSE strasse Straße Οδυσσεύς Οδυσσευς οδυσσευσ"); len(words) != 1 || words[0].Text != "Οδυσσεύς" {
+		t.Errorf("Rules.Words failed: got (%v) expected only (Οδυσσεύς) left", words)
+	}
+
+	for word, expected := range map[string]bool{"etc.": true, "(Etc.": true, "etc": false, "chat.": false} {
+		if result := rules.IsAbbreviation(word); result != expected {
+			t.Errorf("IsAbbreviation(%s) failed: got (%t) expected (%t)", word, result, expected)
+		}
+	}
+}
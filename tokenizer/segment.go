@@ -0,0 +1,179 @@
+package tokenizer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chinese and Japanese don't put spaces between words, so Tokenize gives back a whole clause
+// as one WORD. A Segmenter cuts such a run into words.
+
+type Segmenter interface {
+	// Segment splits a run of CJK text into words, the words put back together are the run again.
+	Segment(run string) []string
+}
+
+// IsCJK is true for the scripts that are written without spaces.
+// Hangul isn't here, Korean puts spaces between words.
+func IsCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) || r == 'ー' || r == '々'
+}
+
+// RuneSegmenter makes every character its own word, it's what we do without a dictionary.
+type RuneSegmenter struct{}
+
+func (RuneSegmenter) Segment(run string) []string {
+	result := make([]string, 0, utf8.RuneCountInString(run))
+	for i, r := range run {
+		result = append(result, run[i:i+utf8.RuneLen(r)])
+	}
+	return result
+}
+
+// MaxMatch is forward maximum matching: at every position take the longest dictionary word that fits,
+// or a single character if nothing does.
+type MaxMatch struct {
+	words   map[string]struct{}
+	max_len int // in runes
+}
+
+func NewMaxMatch(words []string) *MaxMatch {
+	mm := &MaxMatch{words: make(map[string]struct{}, len(words))}
+	for _, w := range words {
+		mm.Add(w)
+	}
+	return mm
+}
+
+func (mm *MaxMatch) Add(word string) {
+	if word == "" {
+		return
+	}
+	mm.words[word] = struct{}{}
+	if n := utf8.RuneCountInString(word); n > mm.max_len {
+		mm.max_len = n
+	}
+}
+
+func (mm *MaxMatch) Len() int {
+	return len(mm.words)
+}
+
+func (mm *MaxMatch) Segment(run string) []string {
+	var result []string
+	for len(run) > 0 {
+		// NOTE: an empty dictionary has max_len 0, every character is its own word then
+		_, size := utf8.DecodeRuneInString(run)
+		// ends[n] is the byte length of the first n+1 runes
+		var ends []int
+		for i, r := range run {
+			if len(ends) == mm.max_len {
+				break
+			}
+			ends = append(ends, i+utf8.RuneLen(r))
+		}
+		for n := len(ends) - 1; n > 0; n-- {
+			if _, ok := mm.words[run[:ends[n]]]; ok {
+				size = ends[n]
+				break
+			}
+		}
+		result = append(result, run[:size])
+		run = run[size:]
+	}
+	return result
+}
+
+// LoadCEDICT reads the headwords of a CC-CEDICT file, both traditional and simplified:
+//
+//	# comment
+//	中國 中国 [Zhong1 guo2] /China/
+func LoadCEDICT(r io.Reader) (*MaxMatch, error) {
+	mm := NewMaxMatch(nil)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) < 3 || !strings.HasPrefix(fields[2], "[") {
+			return nil, fmt.Errorf("cedict line %d: expected 'traditional simplified [pinyin] /definitions/'", lineno)
+		}
+		mm.Add(fields[0])
+		mm.Add(fields[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return mm, nil
+}
+
+func LoadCEDICTFile(path string) (*MaxMatch, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadCEDICT(file)
+}
+
+// Segment splits every WORD that has CJK in it with seg, a nil seg leaves the tokens alone.
+// Only the CJK runs are segmented, "Hello世界" is "Hello", "世界" and then whatever seg makes of it.
+func Segment(tokens []Token, seg Segmenter) []Token {
+	if seg == nil {
+		return tokens
+	}
+	var result []Token
+	for _, tok := range tokens {
+		if tok.Kind != WORD || strings.IndexFunc(tok.Text, IsCJK) == -1 {
+			result = append(result, tok)
+			continue
+		}
+		for _, run := range cjk_runs(tok.Text) {
+			if !run.cjk {
+				result = append(result, Token{Text: run.text, Offset: tok.Offset + run.offset, Kind: WORD})
+				continue
+			}
+			offset := tok.Offset + run.offset
+			for _, word := range seg.Segment(run.text) {
+				result = append(result, Token{Text: word, Offset: offset, Kind: WORD})
+				offset += len(word)
+			}
+		}
+	}
+	return result
+}
+
+type text_run struct {
+	text   string
+	offset int
+	cjk    bool
+}
+
+func cjk_runs(s string) []text_run {
+	var result []text_run
+	start := 0
+	for i, r := range s {
+		cjk := IsCJK(r)
+		if i == 0 {
+			result = append(result, text_run{cjk: cjk})
+			continue
+		}
+		if last := &result[len(result)-1]; cjk != last.cjk {
+			last.text = s[start:i]
+			result = append(result, text_run{offset: i, cjk: cjk})
+			start = i
+		}
+	}
+	if len(result) > 0 {
+		result[len(result)-1].text = s[start:]
+	}
+	return result
+}
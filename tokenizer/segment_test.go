@@ -0,0 +1,78 @@
+package tokenizer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const test_cedict = `# CC-CEDICT
+# test subset
+中國 中国 [Zhong1 guo2] /China/
+中國人 中国人 [Zhong1 guo2 ren2] /Chinese person/
+人 人 [ren2] /person/
+我們 我们 [wo3 men5] /we; us/
+喜歡 喜欢 [xi3 huan5] /to like/
+`
+
+func TestMaxMatch(t *testing.T) {
+	mm, err := LoadCEDICT(strings.NewReader(test_cedict))
+	if err != nil {
+		t.Fatalf("LoadCEDICT failed: %v", err)
+	}
+	if mm.Len() != 9 {
+		t.Errorf("LoadCEDICT failed: got (%d) words expected (9)", mm.Len())
+	}
+	test := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "我们喜欢中国", expected: []string{"我们", "喜欢", "中国"}},
+		{input: "中国人", expected: []string{"中国人"}},
+		{input: "我們是中國人", expected: []string{"我們", "是", "中國人"}},
+		{input: "好", expected: []string{"好"}},
+		{input: "", expected: nil},
+	}
+	for _, tt := range test {
+		if result := mm.Segment(tt.input); !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("TestMaxMatch(%s) failed: got (%v) expected (%v)", tt.input, result, tt.expected)
+		}
+	}
+
+	if _, err := LoadCEDICT(strings.NewReader("not a cedict line\n")); err == nil {
+		t.Errorf("LoadCEDICT failed: expected an error on a bad line")
+	}
+}
+
+func TestMaxMatchEmpty(t *testing.T) {
+	empty, err := LoadCEDICT(strings.NewReader("# CC-CEDICT\n# nothing but comments\n"))
+	if err != nil {
+		t.Fatalf("LoadCEDICT failed: %v", err)
+	}
+	expected := []string{"我", "们", "a"}
+	for _, mm := range []*MaxMatch{empty, NewMaxMatch(nil)} {
+		if result := mm.Segment("我们a"); !reflect.DeepEqual(result, expected) {
+			t.Errorf("TestMaxMatchEmpty failed: got (%v) expected (%v)", result, expected)
+		}
+	}
+}
+
+func TestSegment(t *testing.T) {
+	mm := NewMaxMatch([]string{"我们", "喜欢", "中国"})
+	test := []struct {
+		input    string
+		seg      Segmenter
+		expected []Token
+	}{
+		{input: "我们喜欢中国。", seg: mm, expected: []Token{{"我们", 0, WORD}, {"喜欢", 6, WORD}, {"中国", 12, WORD}, {"。", 18, PUNCT}}},
+		{input: "Hello世界!", seg: RuneSegmenter{}, expected: []Token{{"Hello", 0, WORD}, {"世", 5, WORD}, {"界", 8, WORD}, {"!", 11, PUNCT}}},
+		{input: "すしを食べた", seg: RuneSegmenter{}, expected: []Token{{"す", 0, WORD}, {"し", 3, WORD}, {"を", 6, WORD}, {"食", 9, WORD}, {"べ", 12, WORD}, {"た", 15, WORD}}},
+		{input: "l'homme 中国", seg: nil, expected: []Token{{"l'", 0, ELISION}, {"homme", 2, WORD}, {"中国", 8, WORD}}},
+		{input: "한국어 단어", seg: RuneSegmenter{}, expected: []Token{{"한국어", 0, WORD}, {"단어", 10, WORD}}},
+	}
+	for _, tt := range test {
+		if result := Segment(Tokenize(tt.input), tt.seg); !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("TestSegment(%s) failed: got (%v) expected (%v)", tt.input, result, tt.expected)
+		}
+	}
+}
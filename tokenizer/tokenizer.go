@@ -0,0 +1,122 @@
+// Package tokenizer splits text into words, numbers and punctuation using unicode categories,
+// so "été", "l'homme", "forget-me-not" and "3,14" come out the way a reader would expect.
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+)
+
+type Kind uint8
+
+const (
+	WORD Kind = iota
+	NUMBER
+	PUNCT
+	ELISION // "l'" in "l'homme", only with Rules.Elisions
+)
+
+func (k Kind) String() string {
+	switch k {
+	case WORD:
+		return "word"
+	case NUMBER:
+		return "number"
+	case PUNCT:
+		return "punct"
+	case ELISION:
+		return "elision"
+	}
+	return "unknown"
+}
+
+type Token struct {
+	Text   string
+	Offset int // byte offset of Text in the tokenized string
+	Kind   Kind
+}
+
+// French elisions, DEFAULT_RULES splits them off so "l'homme" is "homme" in the vocabulary.
+// A rules file without an "elisions" rule has none, "l'homme" and "qu'il" stay one token there.
+var DEFAULT_ELISIONS = []string{"c", "d", "j", "l", "m", "n", "s", "t", "qu", "jusqu", "lorsqu", "puisqu", "quoiqu", "presqu"}
+
+const (
+	APOSTROPHES = "'’ʼ"
+	HYPHENS     = "-‐‑"
+	DECIMAL     = ".,"
+)
+
+// IsLetter is what counts as part of a word: letters and the combining marks that go on them.
+func IsLetter(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r)
+}
+
+// Fold is unicode case folding, "Maison" and "MAISON" are "maison", "Straße" is "strasse".
+func Fold(word string) string {
+	return cases.Fold().String(word) // NOTE: a Caser isn't safe for concurrent use, so no shared one
+}
+
+func is_word_rune(r rune) bool {
+	return IsLetter(r) || unicode.IsNumber(r)
+}
+
+// Tokenize returns every word, number and punctuation run in s with DEFAULT_RULES, spaces are dropped.
+//
+// Inside a word an apostrophe is kept when a letter follows it ("l'homme", "free'd"),
+// a hyphen when a letter or digit follows it ("forget-me-not", "covid-19"),
+// and '.' or ',' only between two digits ("3.14", "1,000").
+func Tokenize(s string) []Token {
+	return DEFAULT_RULES.Tokenize(s)
+}
+
+// Words is Tokenize without the numbers and the punctuation.
+func Words(s string) []Token {
+	return DEFAULT_RULES.Words(s)
+}
+
+// Trim cuts everything that isn't a letter or a digit off both ends of w: "...word!" is "word".
+// It replaces the old ASCII trim list ",.\n\r\\/\"'-;%^$#*@(!?)_-+=:<>[]{}~|".
+func Trim(w string) string {
+	return DEFAULT_RULES.Trim(w)
+}
+
+func decode(s string) (rune, int) {
+	return utf8.DecodeRuneInString(s)
+}
+
+func HasNonAlpha(str string) bool {
+	for _, r := range str {
+		if !IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func AllNonAlpha(str string) bool {
+	for _, r := range str {
+		if IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// SplitElision splits "l'homme" into "l'" and "homme" when the part before the apostrophe is one of prefixes,
+// otherwise the prefix is "" and the word comes back as it is ("free'd", "aujourd'hui").
+func SplitElision(word string, prefixes []string) (string, string) {
+	i := strings.IndexAny(word, APOSTROPHES)
+	if i <= 0 {
+		return "", word
+	}
+	_, size := utf8.DecodeRuneInString(word[i:])
+	for _, prefix := range prefixes {
+		if strings.EqualFold(word[:i], prefix) {
+			return word[:i+size], word[i+size:]
+		}
+	}
+	return "", word
+}
@@ -0,0 +1,95 @@
+package tokenizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	test := []struct {
+		input    string
+		expected []Token
+	}{
+		{input: "", expected: nil},
+		{input: "  \n", expected: nil},
+		{input: "one", expected: []Token{{"one", 0, WORD}}},
+		{input: "...word", expected: []Token{{"...", 0, PUNCT}, {"word", 3, WORD}}},
+		{input: "one..", expected: []Token{{"one", 0, WORD}, {"..", 3, PUNCT}}},
+		{input: "l'homme", expected: []Token{{"l'", 0, ELISION}, {"homme", 2, WORD}}},
+		{input: "l'homme qu’il", expected: []Token{{"l'", 0, ELISION}, {"homme", 2, WORD}, {"qu’", 8, ELISION}, {"il", 13, WORD}}},
+		{input: "aujourd'hui free'd", expected: []Token{{"aujourd'hui", 0, WORD}, {"free'd", 12, WORD}}},
+		{input: "l’été", expected: []Token{{"l’", 0, ELISION}, {"été", 4, WORD}}},
+		{input: "forget-me-not", expected: []Token{{"forget-me-not", 0, WORD}}},
+		{input: "one!!!---", expected: []Token{{"one", 0, WORD}, {"!!!---", 3, PUNCT}}},
+		{input: "'quoted'", expected: []Token{{"'", 0, PUNCT}, {"quoted", 1, WORD}, {"'", 7, PUNCT}}},
+		{input: "3.14 1,000 covid-19", expected: []Token{{"3.14", 0, NUMBER}, {"1,000", 5, NUMBER}, {"covid-19", 11, WORD}}},
+		{input: "end. 42", expected: []Token{{"end", 0, WORD}, {".", 3, PUNCT}, {"42", 5, NUMBER}}},
+		{input: "a[b]^c_d`e", expected: []Token{
+			{"a", 0, WORD}, {"[", 1, PUNCT}, {"b", 2, WORD}, {"]^", 3, PUNCT}, {"c", 5, WORD}, {"_", 6, PUNCT}, {"d", 7, WORD}, {"`", 8, PUNCT}, {"e", 9, WORD},
+		}},
+		{input: "«Où?»", expected: []Token{{"«", 0, PUNCT}, {"Où", 2, WORD}, {"?»", 5, PUNCT}}},
+		{input: "naïve café", expected: []Token{{"naïve", 0, WORD}, {"café", 7, WORD}}},
+	}
+	for _, tt := range test {
+		if result := Tokenize(tt.input); !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("TestTokenize(%q) failed: got (%v) expected (%v)", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestTrim(t *testing.T) {
+	test := []struct {
+		input    string
+		expected string
+	}{
+		{input: "one,", expected: "one"},
+		{input: "two...", expected: "two"},
+		{input: "...word", expected: "word"},
+		{input: "\"Bilbo!\"", expected: "Bilbo"},
+		{input: "six-o-clock", expected: "six-o-clock"},
+		{input: "«été»", expected: "été"},
+		{input: "_^`", expected: ""},
+	}
+	for _, tt := range test {
+		if result := Trim(tt.input); result != tt.expected {
+			t.Errorf("TestTrim(%q) failed: got (%s) expected (%s)", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestHasNonAlpha(t *testing.T) {
+	test := []struct {
+		input    string
+		has, all bool
+	}{
+		{input: "maison", has: false, all: false},
+		{input: "été", has: false, all: false},
+		{input: "l'homme", has: true, all: false},
+		{input: "a^b", has: true, all: false},
+		{input: "_`[]", has: true, all: true},
+		{input: "...", has: true, all: true},
+	}
+	for _, tt := range test {
+		if has, all := HasNonAlpha(tt.input), AllNonAlpha(tt.input); has != tt.has || all != tt.all {
+			t.Errorf("TestHasNonAlpha(%q) failed: got (%t, %t) expected (%t, %t)", tt.input, has, all, tt.has, tt.all)
+		}
+	}
+}
+
+func TestSplitElision(t *testing.T) {
+	test := []struct {
+		input, prefix, word string
+	}{
+		{input: "l'homme", prefix: "l'", word: "homme"},
+		{input: "Qu'il", prefix: "Qu'", word: "il"},
+		{input: "jusqu’ici", prefix: "jusqu’", word: "ici"},
+		{input: "free'd", prefix: "", word: "free'd"},
+		{input: "aujourd'hui", prefix: "", word: "aujourd'hui"},
+		{input: "homme", prefix: "", word: "homme"},
+	}
+	for _, tt := range test {
+		if prefix, word := SplitElision(tt.input, DEFAULT_ELISIONS); prefix != tt.prefix || word != tt.word {
+			t.Errorf("TestSplitElision(%q) failed: got (%s, %s) expected (%s, %s)", tt.input, prefix, word, tt.prefix, tt.word)
+		}
+	}
+}
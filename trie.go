@@ -1,243 +1,183 @@
 package main
 
-const R = 26
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// A trie of words keyed by runes, children are kept sorted so everything comes out in lexicographic order.
+// NOTE: runes sort the same way as their UTF-8 bytes do, so that's the same order as sort.Strings.
+// Invalid UTF-8 turns into utf8.RuneError like it does with range.
 
 type TrieNode struct {
-	key         string
+	key         rune
 	end_of_word bool
-	paths       int32
-	children    [R]*TrieNode
+	paths       int32       // words that end here or below, a node with 0 paths is gone
+	children    []*TrieNode // sorted by key
 }
 
 func NewTrie() *TrieNode {
 	return &TrieNode{}
 }
 
-func MakeNull(trie *TrieNode) {
-	trie.key = ""
-	trie.end_of_word = false
-	trie.paths = 0
-	for i := 0; i < len(trie.children); i++ {
-		trie.children[i] = nil
+// child returns the child for r, or the index where it would have to go.
+func (trie *TrieNode) child(r rune) (*TrieNode, int) {
+	i := sort.Search(len(trie.children), func(i int) bool { return trie.children[i].key >= r })
+	if i < len(trie.children) && trie.children[i].key == r {
+		return trie.children[i], i
 	}
+	return nil, i
 }
 
-func (trie *TrieNode) KeysWithPrefix(prefix string) {
-	var curr_index int8
-	var current *TrieNode
-
-	current = trie
-	for i := 0; i < len(prefix); i++ {
-		curr_index = __get_ascii_index(prefix[i])
-		current = current.children[curr_index]
-	}
-
-	for _, node := range GetChildren(current) {
-		Traverse(prefix, node)
+func (trie *TrieNode) find(prefix string) *TrieNode {
+	current := trie
+	for _, r := range prefix {
+		if current, _ = current.child(r); current == nil {
+			return nil
+		}
 	}
+	return current
 }
 
-func GetChildren(trie *TrieNode) []*TrieNode {
-	var children []*TrieNode
-	for index := range trie.children {
-		if trie.children[index] != nil {
-			children = append(children, trie.children[index])
-		}
-	}
-	return children
+// Len is the number of words in the trie.
+func (trie *TrieNode) Len() int {
+	return int(trie.paths)
 }
 
-func GetSibling(trie *TrieNode, index int) *TrieNode {
-	if index > len(trie.children)-1 {
-		return nil
+func (trie *TrieNode) Insert(word string) {
+	if trie.Search(word) {
+		return // NOTE: paths only count a word once, "mais" after "maison" is still one more word
 	}
 	current := trie
-	for i := index + 1; i < len(current.children); i++ {
-		if current.children[i] != nil {
-			return current.children[i]
+	current.paths += 1
+	for _, r := range word {
+		next, i := current.child(r)
+		if next == nil {
+			next = &TrieNode{key: r}
+			current.children = append(current.children, nil)
+			copy(current.children[i+1:], current.children[i:])
+			current.children[i] = next
 		}
+		current = next
+		current.paths += 1
 	}
-	return nil
+	current.end_of_word = true
 }
 
-func Traverse(prefix string, trie *TrieNode) {
-	var path []*TrieNode
-	var current *TrieNode
-	var prev *TrieNode
-	var children []*TrieNode
-	var nloops int
-
-	current = trie
-
-	path = append(path, current)
+func (trie *TrieNode) Search(word string) bool {
+	node := trie.find(word)
+	return node != nil && node.end_of_word
+}
 
-	if current.paths > 1 {
-		children = GetChildren(current)
-		nloops = len(children)
-	} else {
-		nloops = 1
+// Delete removes word and the nodes only it was using, it returns false if word wasn't there.
+// The shared prefix stays: deleting "maison" leaves "mais" alone and only cuts off "on".
+func (trie *TrieNode) Delete(word string) bool {
+	if !trie.Search(word) {
+		return false
 	}
-
-	goback := 0
-	revert := len(prefix)
-	for i := 0; i < nloops; i++ {
-		if len(children) > 1 {
-			current = children[i]
-			path = append(path, current)
-			revert += 1
-		}
-		for {
-			if current.end_of_word && current.paths > 0 {
-				print(prefix)
-				for i := range path {
-					print(path[i].key)
-				}
-				println("")
-			}
-
-			for index := range current.children {
-				if current.children[index] != nil {
-					if current.paths > 1 {
-						prev = GetSibling(current, index)
-						goback = revert
-					}
-					current = current.children[index]
-					path = append(path, current)
-					revert += 1
-					break
-				}
-			}
-
-			if current.paths == 0 {
-				print(prefix)
-				for i := range path {
-					print(path[i].key)
-				}
-				println("")
-				if prev != nil {
-					LEN := len(path)
-					for rm := goback; rm < LEN; rm++ {
-						path = append(path[0:0], path[:len(path)-1]...)
-					}
-					current = prev
-					path = append(path, current)
-					prev = nil
-					revert = goback + 1
-				} else {
-					break
-				}
-			}
-		}
-		for rm := len(path); rm > len(prefix); rm-- {
-			path = append(path[0:0], path[:len(path)-1]...)
+	current := trie
+	current.paths -= 1
+	for _, r := range word {
+		next, i := current.child(r)
+		if next.paths == 1 {
+			// word is the only one left below here, so the rest of the path goes with it
+			current.children = append(current.children[:i], current.children[i+1:]...)
+			return true
 		}
+		current = next
+		current.paths -= 1
 	}
+	current.end_of_word = false
+	return true
 }
 
-func (trie *TrieNode) Search(word string) bool {
-	var curr_index int8
-	var current *TrieNode
+// KeysWithPrefix returns the words that start with prefix in lexicographic order,
+// at most limit of them (limit <= 0 is all of them).
+func (trie *TrieNode) KeysWithPrefix(prefix string, limit int) []string {
+	var result []string
+	node := trie.find(prefix)
+	if node == nil {
+		return result
+	}
+	node.walk([]byte(prefix), func(word string) bool {
+		result = append(result, word)
+		return limit <= 0 || len(result) < limit
+	})
+	return result
+}
 
-	curr_index = __get_ascii_index(word[0])
-	current = trie.children[curr_index]
+// Walk calls fn for every word in lexicographic order until fn returns false.
+func (trie *TrieNode) Walk(fn func(word string) bool) {
+	trie.walk(nil, fn)
+}
 
-	if current == nil {
+// walk is depth first, a word comes before the longer words it's a prefix of.
+func (trie *TrieNode) walk(word []byte, fn func(word string) bool) bool {
+	if trie.end_of_word && !fn(string(word)) {
 		return false
 	}
-	for i := 1; i < len(word); i++ {
-		curr_index = __get_ascii_index(word[i])
-		current = current.children[curr_index]
-		if current == nil {
+	for _, child := range trie.children {
+		if !child.walk(utf8.AppendRune(word, child.key), fn) {
 			return false
 		}
 	}
-	if current.end_of_word == true {
-		return true
-	}
-	return false
+	return true
 }
 
-func (trie *TrieNode) Delete(word string) {
-	var current *TrieNode
-	var nodes []*TrieNode
-
-	current = trie.children[__get_ascii_index(word[0])]
-	nodes = append(nodes, current)
-	for i := 1; i < len(word); i++ {
-		current = current.children[__get_ascii_index(word[i])]
-		nodes = append(nodes, current)
-		if current.paths >= 1 && current.end_of_word {
-			current.end_of_word = false
-		}
-		if current.paths == 0 && current.end_of_word {
-			for i := 0; i < len(nodes)-1; i++ {
-				MakeNull(nodes[i])
-			}
-		}
+type FuzzyMatch struct {
+	Word     string
+	Distance int // Levenshtein, in runes
+	Count    int // how often it's in the text, see RankFuzzyMatches
+}
+
+// SearchWithin returns the words that are at most k edits (insert, delete, replace a rune) away from word,
+// in lexicographic order. It's the Levenshtein table of word against every path of the trie, one row per node,
+// and a whole branch is skipped once every cell of its row is over k.
+func (trie *TrieNode) SearchWithin(word string, k int) []FuzzyMatch {
+	var result []FuzzyMatch
+	target := []rune(word)
+	row := make([]int, len(target)+1)
+	for i := range row {
+		row[i] = i
+	}
+	for _, child := range trie.children {
+		child.search_within(utf8.AppendRune(nil, child.key), target, row, k, &result)
 	}
+	return result
 }
 
-func (trie *TrieNode) Insert(word string) {
-	curr_char := 0
-	curr_index := __get_ascii_index(word[0])
-	add_new_nodes := true
-	var current *TrieNode
-
-	if trie.children[curr_index] == nil {
-		trie.children[curr_index] = new(TrieNode)
-		trie.paths += 1
-		current = trie.children[curr_index]
-		current.key = string(word[0])
-		curr_char += 1
-	} else {
-		current = trie.children[curr_index]
-		for current.key == string(word[curr_char]) || (current.end_of_word != true) {
-			if curr_char == len(word)-1 {
-				if current.end_of_word == true {
-					add_new_nodes = false
-					break
-				}
-				current.end_of_word = true
-				add_new_nodes = false
-				break
-			}
-			curr_char += 1
-			curr_index = __get_ascii_index(word[curr_char])
-			if current.children[curr_index] == nil {
-				break
-			}
-			current = current.children[curr_index]
+func (trie *TrieNode) search_within(word []byte, target []rune, prev []int, k int, result *[]FuzzyMatch) {
+	row := make([]int, len(prev))
+	row[0] = prev[0] + 1
+	best := row[0]
+	for i := 1; i < len(row); i++ {
+		replace := prev[i-1]
+		if target[i-1] != trie.key {
+			replace += 1
 		}
+		row[i] = min_int(replace, min_int(row[i-1], prev[i])+1)
+		best = min_int(best, row[i])
 	}
-	if add_new_nodes {
-		for i := curr_char; i < len(word); i++ {
-			curr_index = __get_ascii_index(word[i])
-			current.children[curr_index] = new(TrieNode)
-			current.paths += 1
-			current = current.children[curr_index]
-			current.key = string(word[i])
-		}
-		current.end_of_word = true
+	if trie.end_of_word && row[len(row)-1] <= k {
+		*result = append(*result, FuzzyMatch{Word: string(word), Distance: row[len(row)-1]})
+	}
+	if best > k {
+		return
+	}
+	for _, child := range trie.children {
+		child.search_within(utf8.AppendRune(word, child.key), target, row, k, result)
 	}
 }
 
-func __get_ascii_index(char byte) int8 {
-	if ((char >= 'A') && (char <= 'z')) == false {
-		return -1
-	}
-	return map[byte]int8{
-		'a': 0, 'b': 1, 'c': 2, 'd': 3,
-		'e': 4, 'f': 5, 'g': 6, 'h': 7,
-		'i': 8, 'j': 9, 'k': 10, 'l': 11,
-		'm': 12, 'n': 13, 'o': 14, 'p': 15,
-		'q': 16, 'r': 17, 's': 18, 't': 19,
-		'u': 20, 'v': 21, 'w': 22, 'x': 23,
-		'y': 24, 'z': 25, 'A': 26, 'B': 27,
-		'C': 28, 'D': 29, 'E': 30, 'F': 31,
-		'G': 32, 'H': 33, 'I': 34, 'J': 35,
-		'K': 36, 'L': 37, 'M': 38, 'N': 39,
-		'O': 40, 'P': 41, 'Q': 42, 'R': 43,
-		'S': 44, 'T': 45, 'U': 46, 'V': 47,
-		'W': 48, 'X': 49, 'Y': 50, 'Z': 51,
-	}[char]
+// RankFuzzyMatches puts the closest matches first, and the ones that are in the text more often first among those.
+func RankFuzzyMatches(matches []FuzzyMatch, counts map[string]int) {
+	for i := range matches {
+		matches[i].Count = counts[matches[i].Word]
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].Count > matches[j].Count
+	})
 }
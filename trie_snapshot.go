@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"sort"
+)
+
+// The vocabulary trie is kept in BUCKET_TRIE/<lang> so we don't have to decode every WordRecord on startup.
+// The sorted word list is cut into blocks of up to TRIE_BLOCK_SIZE words, each block is stored under
+// its first word, so adding a word only rewrites the block it falls into.
+//
+// A block is front coded, every word only stores what it doesn't share with the one before it:
+//
+//	[version byte][uvarint count] then count times [uvarint shared][uvarint len(suffix)][suffix]
+//
+// NOTE: a language without a snapshot bucket just doesn't have one yet, DBLoadTrie builds it from
+// the vocabulary the first time. Writes to a language that has a snapshot must go through snapshot_add.
+
+var BUCKET_TRIE = []byte("Trie")
+
+const TRIE_BLOCK_VERSION uint8 = 1
+
+// a full block is split in half, so blocks hold TRIE_BLOCK_SIZE/2 to TRIE_BLOCK_SIZE words
+const TRIE_BLOCK_SIZE = 256
+
+var BrokenTrieBlockError = errors.New("broken trie block")
+
+// EncodeFrontCoded expects words to be sorted.
+func EncodeFrontCoded(words []string) []byte {
+	data := []byte{TRIE_BLOCK_VERSION}
+	data = binary.AppendUvarint(data, uint64(len(words)))
+	prev := ""
+	for _, word := range words {
+		shared := 0
+		for shared < len(prev) && shared < len(word) && prev[shared] == word[shared] {
+			shared += 1
+		}
+		data = binary.AppendUvarint(data, uint64(shared))
+		data = binary.AppendUvarint(data, uint64(len(word)-shared))
+		data = append(data, word[shared:]...)
+		prev = word
+	}
+	return data
+}
+
+func DecodeFrontCoded(data []byte) ([]string, error) {
+	if len(data) == 0 || data[0] != TRIE_BLOCK_VERSION {
+		return nil, fmt.Errorf("%w: unknown version", BrokenTrieBlockError)
+	}
+	data = data[1:]
+	count, n := binary.Uvarint(data)
+	if n <= 0 || count > uint64(len(data)) { // every word takes at least 2 bytes
+		return nil, fmt.Errorf("%w: bad count", BrokenTrieBlockError)
+	}
+	data = data[n:]
+	words := make([]string, 0, count)
+	var word []byte
+	for i := uint64(0); i < count; i++ {
+		shared, n := binary.Uvarint(data)
+		if n <= 0 || shared > uint64(len(word)) {
+			return nil, fmt.Errorf("%w: bad prefix of word %d", BrokenTrieBlockError, i)
+		}
+		data = data[n:]
+		size, n := binary.Uvarint(data)
+		if n <= 0 || size > uint64(len(data)-n) {
+			return nil, fmt.Errorf("%w: bad suffix of word %d", BrokenTrieBlockError, i)
+		}
+		data = data[n:]
+		word = append(word[:shared], data[:size]...)
+		data = data[size:]
+		words = append(words, string(word))
+	}
+	return words, nil
+}
+
+// snapshot_bucket returns nil if lang has no snapshot (yet).
+func snapshot_bucket(tx *bolt.Tx, lang string) *bolt.Bucket {
+	if snapshots := tx.Bucket(BUCKET_TRIE); snapshots != nil {
+		return snapshots.Bucket([]byte(lang))
+	}
+	return nil
+}
+
+// snapshot_add keeps the snapshot of lang in sync with words added to the vocabulary in the same tx.
+func snapshot_add(tx *bolt.Tx, lang string, words ...string) error {
+	bucket := snapshot_bucket(tx, lang)
+	if bucket == nil {
+		return nil
+	}
+	for _, word := range words {
+		if err := snapshot_add_word(bucket, word); err != nil {
+			return fmt.Errorf("Failed to add '%s' to the trie snapshot: '%w'", word, err)
+		}
+	}
+	return nil
+}
+
+func snapshot_add_word(bucket *bolt.Bucket, word string) error {
+	c := bucket.Cursor()
+	k, v := c.Seek([]byte(word))
+	if k != nil && string(k) == word {
+		return nil // it's the first word of a block
+	}
+	// the word goes into the block before the one we landed on, or the first block if it's smaller than everything
+	if k == nil {
+		k, v = c.Last()
+	} else if pk, pv := c.Prev(); pk != nil {
+		k, v = pk, pv
+	}
+	var words []string
+	if k != nil {
+		var err error
+		if words, err = DecodeFrontCoded(v); err != nil {
+			return err
+		}
+		k = append([]byte(nil), k...)
+	}
+	i := sort.SearchStrings(words, word)
+	if i < len(words) && words[i] == word {
+		return nil
+	}
+	words = append(words, "")
+	copy(words[i+1:], words[i:])
+	words[i] = word
+
+	if k != nil && i == 0 {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+	if len(words) > TRIE_BLOCK_SIZE {
+		half := len(words) / 2
+		if err := bucket.Put([]byte(words[half]), EncodeFrontCoded(words[half:])); err != nil {
+			return err
+		}
+		words = words[:half]
+	}
+	return bucket.Put([]byte(words[0]), EncodeFrontCoded(words))
+}
+
+// snapshot_write replaces the snapshot of lang with words, they have to be sorted.
+func snapshot_write(tx *bolt.Tx, lang string, words []string) error {
+	snapshots, err := tx.CreateBucketIfNotExists(BUCKET_TRIE)
+	if err != nil {
+		return fmt.Errorf("Failed to create bucket: %w", err)
+	}
+	if snapshots.Bucket([]byte(lang)) != nil {
+		if err = snapshots.DeleteBucket([]byte(lang)); err != nil {
+			return err
+		}
+	}
+	bucket, err := snapshots.CreateBucket([]byte(lang))
+	if err != nil {
+		return fmt.Errorf("Failed to create bucket '%s': %w", lang, err)
+	}
+	for len(words) > 0 {
+		n := TRIE_BLOCK_SIZE
+		if n > len(words) {
+			n = len(words)
+		}
+		if err = bucket.Put([]byte(words[0]), EncodeFrontCoded(words[:n])); err != nil {
+			return err
+		}
+		words = words[n:]
+	}
+	return nil
+}
+
+// DBLoadTrie reads the trie of lang from its snapshot. The first time (or if the snapshot is broken)
+// it builds the trie from the vocabulary and writes the snapshot for next time.
+func DBLoadTrie(db *bolt.DB, lang string) (*TrieNode, error) {
+	if lang == "" {
+		return nil, EmptyLanguageError
+	}
+	trie := NewTrie()
+	found := false
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := snapshot_bucket(tx, lang)
+		if bucket == nil {
+			return nil
+		}
+		found = true
+		return bucket.ForEach(func(k, v []byte) error {
+			words, err := DecodeFrontCoded(v)
+			if err != nil {
+				return err
+			}
+			for _, word := range words {
+				trie.Insert(word)
+			}
+			return nil
+		})
+	})
+	if errors.Is(err, BrokenTrieBlockError) {
+		fmt.Printf("[WARNING] rebuilding the '%s' trie snapshot: %v\n", lang, err)
+		trie, found, err = NewTrie(), false, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bbolt db.View in DBLoadTrie failed '%w'", err)
+	}
+	if found {
+		return trie, nil
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := lang_bucket(tx, lang)
+		if err != nil {
+			return err
+		}
+		var words []string // NOTE: bbolt hands out the keys sorted already
+		err = bucket.ForEach(func(k, v []byte) error {
+			words = append(words, string(k))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, word := range words {
+			trie.Insert(word)
+		}
+		return snapshot_write(tx, lang, words)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("bbolt db.Update in DBLoadTrie failed '%w'", err)
+	}
+	return trie, nil
+}
+
+// drop_trie_snapshots makes DBLoadTrie rebuild every snapshot, for when words were changed behind their back.
+func drop_trie_snapshots(tx *bolt.Tx) error {
+	if tx.Bucket(BUCKET_TRIE) == nil {
+		return nil
+	}
+	return tx.DeleteBucket(BUCKET_TRIE)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// snapshot_words is every word in the snapshot of lang, it also checks every block is keyed by its first word.
+func snapshot_words(db *bolt.DB, lang string) ([]string, error) {
+	var result []string
+	err := db.View(func(tx *bolt.Tx) error {
+		bucket := snapshot_bucket(tx, lang)
+		if bucket == nil {
+			return fmt.Errorf("no snapshot for '%s'", lang)
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			words, err := DecodeFrontCoded(v)
+			if err != nil {
+				return err
+			}
+			if len(words) == 0 || len(words) > TRIE_BLOCK_SIZE || !bytes.Equal(k, []byte(words[0])) {
+				return fmt.Errorf("bad block '%s' with %d words", k, len(words))
+			}
+			result = append(result, words...)
+			return nil
+		})
+	})
+	return result, err
+}
+
+func vocab_words(db *bolt.DB, lang string) []string {
+	var result []string
+	DBForEachWord(db, lang, func(word string, rec WordRecord) error {
+		result = append(result, word)
+		return nil
+	})
+	return result
+}
+
+// random_vocabulary never has an empty word, bbolt doesn't take empty keys.
+func random_vocabulary(r *rand.Rand, n int) []string {
+	const letters = "abcdeéèfghij"
+	letter_runes := []rune(letters)
+	var result []string
+	for i := 0; i < n; i++ {
+		word := make([]rune, 1+r.Intn(10))
+		for j := range word {
+			word[j] = letter_runes[r.Intn(len(letter_runes))]
+		}
+		result = append(result, string(word))
+	}
+	return result
+}
+
+func TestFrontCoded(t *testing.T) {
+	test := [][]string{
+		nil,
+		{"a"},
+		{"mais", "maison", "maisonnette", "maître", "maîtresse", "zèbre"},
+		{"", "a", "ab", "abc", "b"},
+	}
+	for _, words := range test {
+		out, err := DecodeFrontCoded(EncodeFrontCoded(words))
+		if err != nil || len(out) != len(words) || (len(words) > 0 && !reflect.DeepEqual(out, words)) {
+			t.Errorf("TestFrontCoded failed: got (%q, %v) expected (%q)", out, err, words)
+		}
+	}
+	data := EncodeFrontCoded([]string{"maison", "maisonnette"})
+	if size := len(data); size != 1+1+2+6+2+5 {
+		t.Errorf("EncodeFrontCoded failed: got (%d) bytes expected (17)", size)
+	}
+	for _, broken := range [][]byte{nil, {0}, data[:len(data)-1], {TRIE_BLOCK_VERSION, 1, 3, 0}} {
+		if _, err := DecodeFrontCoded(broken); !errors.Is(err, BrokenTrieBlockError) {
+			t.Errorf("DecodeFrontCoded(%v) failed: got (%v) expected (%v)", broken, err, BrokenTrieBlockError)
+		}
+	}
+}
+
+func TestDBLoadTrie(t *testing.T) {
+	db := openTestDB(t)
+	DBInit(db, "fr", map[string]WordContext{"maison": {}, "chat": {}})
+
+	trie, err := DBLoadTrie(db, "fr")
+	if err != nil || trie.Len() != 2 || !trie.Search("maison") {
+		t.Fatalf("DBLoadTrie failed: got (%v, %v)", trie.KeysWithPrefix("", 0), err)
+	}
+
+	// every way into the vocabulary has to keep the snapshot in sync
+	DBInsert(db, "fr", "abeille")
+	DBInit(db, "fr", map[string]WordContext{"zèbre": {}, "maison": {}})
+	DBPutWord(db, "fr", "main", WordRecord{})
+	DBUpdateWord(db, "fr", "mais", func(rec *WordRecord) error { return nil })
+	DBMergeWords(db, "fr", map[string]WordRecord{"chien": {}, "chat": {Note: "cat"}})
+	DBInsert(db, "de", "haus")
+
+	words, err := snapshot_words(db, "fr")
+	if err != nil || !reflect.DeepEqual(words, vocab_words(db, "fr")) {
+		t.Errorf("TestDBLoadTrie failed: snapshot (%q, %v) expected (%q)", words, err, vocab_words(db, "fr"))
+	}
+	if trie, _ = DBLoadTrie(db, "fr"); trie.Len() != 7 || trie.Search("haus") {
+		t.Errorf("DBLoadTrie failed: got (%q)", trie.KeysWithPrefix("", 0))
+	}
+
+	// a broken snapshot is rebuilt
+	db.Update(func(tx *bolt.Tx) error {
+		return snapshot_bucket(tx, "fr").Put([]byte("chat"), []byte{TRIE_BLOCK_VERSION, 9})
+	})
+	if trie, err = DBLoadTrie(db, "fr"); err != nil || trie.Len() != 7 {
+		t.Errorf("DBLoadTrie didn't rebuild a broken snapshot: got (%q, %v)", trie.KeysWithPrefix("", 0), err)
+	}
+	if words, err = snapshot_words(db, "fr"); err != nil || len(words) != 7 {
+		t.Errorf("DBLoadTrie didn't rewrite a broken snapshot: got (%q, %v)", words, err)
+	}
+}
+
+func TestDBLoadTrieWrapsErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := bolt.Open(path, FILE_MODE_RW, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	DBInit(db, "fr", map[string]WordContext{"maison": {}})
+	db.Update(func(tx *bolt.Tx) error { return tx.DeleteBucket(BUCKET_TRIE) })
+	db.Close()
+
+	// NOTE: without a snapshot DBLoadTrie has to write one, which a read-only db refuses
+	db, err = bolt.Open(path, FILE_MODE_RW, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := DBLoadTrie(db, "fr"); !errors.Is(err, bolt.ErrDatabaseReadOnly) {
+		t.Errorf("DBLoadTrie failed: got (%v) expected (%v)", err, bolt.ErrDatabaseReadOnly)
+	}
+}
+
+func TestSnapshotAddSplitsBlocks(t *testing.T) {
+	db := openTestDB(t)
+	DBLoadTrie(db, "fr") // an empty snapshot
+
+	r := rand.New(rand.NewSource(1))
+	words := random_vocabulary(r, 4*TRIE_BLOCK_SIZE)
+	for i := 0; i < len(words); i += 50 {
+		batch := make(map[string]WordContext)
+		for _, word := range words[i:min_int(i+50, len(words))] {
+			batch[word] = WordContext{}
+		}
+		if err := DBInit(db, "fr", batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := snapshot_words(db, "fr")
+	if err != nil {
+		t.Fatalf("TestSnapshotAddSplitsBlocks failed: %v", err)
+	}
+	want := vocab_words(db, "fr")
+	if !sort.StringsAreSorted(got) || !reflect.DeepEqual(got, want) {
+		t.Errorf("TestSnapshotAddSplitsBlocks failed: got %d words expected %d", len(got), len(want))
+	}
+}
+
+// the cold start of the console, with a vocabulary about the size of a few years of reading
+const BENCH_VOCABULARY_SIZE = 50000
+
+func bench_db(b *testing.B) *bolt.DB {
+	db, err := bolt.Open(b.TempDir()+"/bench.db", FILE_MODE_RW, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+	words := make(map[string]WordContext)
+	for _, word := range random_vocabulary(rand.New(rand.NewSource(1)), BENCH_VOCABULARY_SIZE) {
+		words[word] = WordContext{File: "HP01.txt", Sentence: "In a hole in the ground there lived a " + word + "."}
+	}
+	if err := DBInit(db, "fr", words); err != nil {
+		b.Fatal(err)
+	}
+	return db
+}
+
+func BenchmarkColdStartWithoutSnapshot(b *testing.B) {
+	db := bench_db(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trie := NewTrie()
+		if err := DBFillTrie(db, "fr", trie); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkColdStartWithSnapshot(b *testing.B) {
+	db := bench_db(b)
+	if _, err := DBLoadTrie(db, "fr"); err != nil { // writes the snapshot
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := DBLoadTrie(db, "fr"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSnapshotInsert(b *testing.B) {
+	db := bench_db(b)
+	DBLoadTrie(db, "fr")
+	words := random_vocabulary(rand.New(rand.NewSource(2)), b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		DBInsert(db, "fr", words[i])
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode/utf8"
+)
+
+func TestTrie(t *testing.T) {
+	trie := NewTrie()
+	for _, word := range []string{"maison", "mais", "maïs", "main", "Main", "中国", "中国人", "été", "a"} {
+		trie.Insert(word)
+	}
+	for word, expected := range map[string]bool{
+		"maison": true, "mais": true, "maïs": true, "Main": true, "中国": true, "中国人": true, "été": true, "a": true,
+		"mai": false, "maisons": false, "中": false, "": false, "b": false,
+	} {
+		if result := trie.Search(word); result != expected {
+			t.Errorf("Search(%s) failed: got (%t) expected (%t)", word, result, expected)
+		}
+	}
+
+	test := []struct {
+		prefix   string
+		limit    int
+		expected []string
+	}{
+		{prefix: "ma", limit: 0, expected: []string{"main", "mais", "maison", "maïs"}},
+		{prefix: "ma", limit: 2, expected: []string{"main", "mais"}},
+		{prefix: "中", limit: 0, expected: []string{"中国", "中国人"}},
+		{prefix: "mais", limit: 0, expected: []string{"mais", "maison"}},
+		{prefix: "x", limit: 0, expected: nil},
+		{prefix: "", limit: 3, expected: []string{"Main", "a", "main"}},
+	}
+	for _, tt := range test {
+		if result := trie.KeysWithPrefix(tt.prefix, tt.limit); !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("KeysWithPrefix(%s, %d) failed: got (%v) expected (%v)", tt.prefix, tt.limit, result, tt.expected)
+		}
+	}
+
+	if !trie.Delete("mais") || trie.Search("mais") || !trie.Search("maison") || trie.Delete("mais") {
+		t.Errorf("Delete(mais) failed")
+	}
+	if !trie.Delete("中国人") || !trie.Search("中国") || len(trie.find("中国").children) != 0 {
+		t.Errorf("Delete(中国人) failed: it should prune 人")
+	}
+}
+
+// random_words uses a small alphabet so that words share a lot of prefixes.
+func random_words(rnd *rand.Rand, n int) []string {
+	alphabet := []rune("abé中")
+	words := make([]string, n)
+	for i := range words {
+		var word strings.Builder
+		for j := rnd.Intn(6); j > 0; j-- {
+			word.WriteRune(alphabet[rnd.Intn(len(alphabet))])
+		}
+		words[i] = word.String()
+	}
+	return words
+}
+
+func check_trie_against_map(t *testing.T, trie *TrieNode, reference map[string]bool) bool {
+	var keys []string
+	for k := range reference {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !trie.Search(k) {
+			t.Logf("Search(%q) is false, expected true", k)
+			return false
+		}
+	}
+	if result := trie.KeysWithPrefix("", 0); !reflect.DeepEqual(result, keys) {
+		t.Logf("KeysWithPrefix(\"\") got (%q) expected (%q)", result, keys)
+		return false
+	}
+	return true
+}
+
+func TestTrieAgainstMap(t *testing.T) {
+	property := func(seed int64) bool {
+		rnd := rand.New(rand.NewSource(seed))
+		trie := NewTrie()
+		reference := make(map[string]bool)
+		for _, word := range random_words(rnd, 50) {
+			trie.Insert(word)
+			reference[word] = true
+		}
+		for _, word := range random_words(rnd, 50) {
+			if trie.Delete(word) != reference[word] {
+				t.Logf("Delete(%q) disagrees with the map", word)
+				return false
+			}
+			delete(reference, word)
+			if trie.Search(word) {
+				t.Logf("Search(%q) is true after Delete", word)
+				return false
+			}
+		}
+		if !check_trie_against_map(t, trie, reference) {
+			return false
+		}
+		for _, prefix := range random_words(rnd, 10) {
+			var expected []string
+			for _, k := range trie.KeysWithPrefix("", 0) {
+				if strings.HasPrefix(k, prefix) {
+					expected = append(expected, k)
+				}
+			}
+			if len(expected) > 3 {
+				expected = expected[:3]
+			}
+			if result := trie.KeysWithPrefix(prefix, 3); !reflect.DeepEqual(result, expected) {
+				t.Logf("KeysWithPrefix(%q, 3) got (%q) expected (%q)", prefix, result, expected)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+
+	// and with whatever strings testing/quick comes up with
+	arbitrary := func(words []string) bool {
+		trie := NewTrie()
+		reference := make(map[string]bool)
+		for _, word := range words {
+			if !strings.ContainsRune(word, utf8.RuneError) && word != "" {
+				trie.Insert(word)
+				reference[word] = true
+			}
+		}
+		return check_trie_against_map(t, trie, reference)
+	}
+	if err := quick.Check(arbitrary, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// check_trie_paths checks that paths counts the words at and below every node, and that nothing is left dangling.
+func check_trie_paths(node *TrieNode, root bool) (int32, bool) {
+	count := int32(0)
+	if node.end_of_word {
+		count = 1
+	}
+	for i, child := range node.children {
+		if i > 0 && node.children[i-1].key >= child.key {
+			return 0, false
+		}
+		n, ok := check_trie_paths(child, false)
+		if !ok {
+			return 0, false
+		}
+		count += n
+	}
+	return count, count == node.paths && (root || count > 0)
+}
+
+func TestTrieLenWalk(t *testing.T) {
+	trie := NewTrie()
+	for _, word := range []string{"maison", "mais", "mais", "maisons", "m"} {
+		trie.Insert(word)
+	}
+	if trie.Len() != 4 {
+		t.Errorf("Len failed: got (%d) expected (4)", trie.Len())
+	}
+	var words []string
+	trie.Walk(func(word string) bool {
+		words = append(words, word)
+		return len(words) < 3
+	})
+	if !reflect.DeepEqual(words, []string{"m", "mais", "maison"}) {
+		t.Errorf("Walk failed: got (%v)", words)
+	}
+
+	// deleting a word in the middle of a chain keeps the words above and below it
+	trie.Delete("maison")
+	if trie.Len() != 3 || !trie.Search("mais") || !trie.Search("maisons") {
+		t.Errorf("Delete(maison) failed: got (%v)", trie.KeysWithPrefix("", 0))
+	}
+	trie.Delete("maisons")
+	if node := trie.find("mais"); node == nil || len(node.children) != 0 {
+		t.Errorf("Delete(maisons) failed: it should prune 'ons'")
+	}
+	if _, ok := check_trie_paths(trie, true); !ok {
+		t.Errorf("TestTrieLenWalk failed: bad paths")
+	}
+	for _, word := range []string{"m", "mais", "nope"} {
+		trie.Delete(word)
+	}
+	if trie.Len() != 0 || len(trie.children) != 0 {
+		t.Errorf("Delete failed: expected an empty trie, got (%d) words and (%d) children", trie.Len(), len(trie.children))
+	}
+}
+
+// FuzzTrie reads ops as a little program: every byte is an insert or a delete (the low bit)
+// of a word made from the next few bytes, and checks the trie against a map after every op.
+func FuzzTrie(f *testing.F) {
+	f.Add([]byte("\x00ab\x01ab"))
+	f.Add([]byte("\x02maison\x02mais\x03mais\x03maison"))
+	f.Add([]byte{0, 0xe4, 0xb8, 0xad, 1, 0xe4})
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		alphabet := []rune("abé中")
+		trie := NewTrie()
+		reference := make(map[string]bool)
+		for len(ops) > 0 {
+			op := ops[0]
+			n := int(op>>1) % 5
+			if n > len(ops)-1 {
+				n = len(ops) - 1
+			}
+			var word strings.Builder
+			for _, b := range ops[1 : n+1] {
+				word.WriteRune(alphabet[int(b)%len(alphabet)])
+			}
+			ops = ops[n+1:]
+
+			if op&1 == 0 {
+				trie.Insert(word.String())
+				reference[word.String()] = true
+			} else {
+				if trie.Delete(word.String()) != reference[word.String()] {
+					t.Fatalf("Delete(%q) disagrees with the map", word.String())
+				}
+				delete(reference, word.String())
+			}
+			if trie.Len() != len(reference) {
+				t.Fatalf("Len got (%d) expected (%d)", trie.Len(), len(reference))
+			}
+			if _, ok := check_trie_paths(trie, true); !ok {
+				t.Fatalf("bad paths after (%q)", word.String())
+			}
+		}
+		if !check_trie_against_map(t, trie, reference) {
+			t.Fatalf("the trie disagrees with the map")
+		}
+	})
+}
+
+func TestTrieSearchWithin(t *testing.T) {
+	trie := NewTrie()
+	for _, word := range []string{"maison", "raison", "saison", "maïs", "mais", "main", "maisonnette", "hobbit", "中国", "中国人"} {
+		trie.Insert(word)
+	}
+	test := []struct {
+		word     string
+		k        int
+		expected map[string]int
+	}{
+		{word: "maison", k: 0, expected: map[string]int{"maison": 0}},
+		{word: "masion", k: 1, expected: map[string]int{}},
+		{word: "masion", k: 2, expected: map[string]int{"maison": 2, "main": 2}},
+		{word: "maisn", k: 1, expected: map[string]int{"maison": 1, "mais": 1, "main": 1}},
+		{word: "mais", k: 1, expected: map[string]int{"mais": 0, "maïs": 1, "main": 1}},
+		{word: "hobit", k: 1, expected: map[string]int{"hobbit": 1}},
+		{word: "中", k: 1, expected: map[string]int{"中国": 1}},
+		{word: "zzz", k: 2, expected: map[string]int{}},
+	}
+	for _, tt := range test {
+		matches := trie.SearchWithin(tt.word, tt.k)
+		got := make(map[string]int)
+		for _, m := range matches {
+			got[m.Word] = m.Distance
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("SearchWithin(%s, %d) failed: got (%v) expected (%v)", tt.word, tt.k, got, tt.expected)
+		}
+	}
+}
+
+// levenshtein is the plain O(n*m) table, SearchWithin has to agree with it on every word
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		row := make([]int, len(rb)+1)
+		row[0] = i
+		for j := 1; j <= len(rb); j++ {
+			replace := prev[j-1]
+			if ra[i-1] != rb[j-1] {
+				replace += 1
+			}
+			row[j] = min_int(replace, min_int(row[j-1], prev[j])+1)
+		}
+		prev = row
+	}
+	return prev[len(rb)]
+}
+
+func TestTrieSearchWithinAgainstLevenshtein(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	trie := NewTrie()
+	words := random_words(rnd, 300)
+	for _, word := range words {
+		trie.Insert(word)
+	}
+	for _, query := range random_words(rnd, 50) {
+		for k := 0; k <= 2; k++ {
+			got := make(map[string]int)
+			for _, m := range trie.SearchWithin(query, k) {
+				got[m.Word] = m.Distance
+			}
+			expected := make(map[string]int)
+			for _, word := range words {
+				if d := levenshtein(query, word); d <= k && word != "" {
+					expected[word] = d
+				}
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("SearchWithin(%q, %d) failed: got (%v) expected (%v)", query, k, got, expected)
+			}
+		}
+	}
+}
+
+func TestRankFuzzyMatches(t *testing.T) {
+	matches := []FuzzyMatch{{Word: "main", Distance: 1}, {Word: "maison", Distance: 1}, {Word: "mais", Distance: 0}, {Word: "maïs", Distance: 1}}
+	RankFuzzyMatches(matches, map[string]int{"maison": 5, "maïs": 2})
+	var got []string
+	for _, m := range matches {
+		got = append(got, m.Word)
+	}
+	if expected := []string{"mais", "maison", "maïs", "main"}; !reflect.DeepEqual(got, expected) || matches[1].Count != 5 {
+		t.Errorf("RankFuzzyMatches failed: got (%v) expected (%v)", matches, expected)
+	}
+}
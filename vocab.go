@@ -0,0 +1,402 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// tester vocab export [-format csv|tsv|anki] [-lang fr] [-out file]
+// tester vocab import [-format csv|tsv|anki] [-lang fr] file
+//
+// csv and tsv carry every field we have, the review history goes in as JSON. The anki format is what Anki's "Import File" understands
+// (word, note, sentence and tags), so status and language travel as "status::known lang::fr" tags
+// and the review fields stay behind, Anki schedules its cards on its own.
+
+const (
+	VOCAB_FORMAT_CSV  = "csv"
+	VOCAB_FORMAT_TSV  = "tsv"
+	VOCAB_FORMAT_ANKI = "anki"
+)
+
+var VOCAB_COLUMNS = []string{
+	"word", "lang", "status", "note", "sentence", "file", "offset",
+	"first_seen", "last_seen", "lookups", "ease", "interval", "reps", "due", "history",
+}
+
+// the "#key:value" lines anki puts at the top of a file
+var ANKI_HEADERS = map[string]bool{
+	"separator": true, "html": true, "columns": true, "tags": true, "tags column": true,
+	"notetype": true, "notetype column": true, "deck": true, "deck column": true, "guid column": true,
+}
+
+type VocabRow struct {
+	Word   string
+	Lang   string
+	Record WordRecord
+}
+
+func RunVocabCommand(db *bolt.DB, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: tester vocab export|import [flags]")
+	}
+	set := flag.NewFlagSet("vocab "+args[0], flag.ContinueOnError)
+	format := set.String("format", VOCAB_FORMAT_CSV, "csv, tsv or anki")
+	lang := set.String("lang", "", "only this language (export) or put everything into this language (import)")
+	out := set.String("out", "", "write the export to this file instead of stdout")
+	if err := set.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "export":
+		rows, err := ReadVocabRows(db, *lang)
+		if err != nil {
+			return err
+		}
+		if *out != "" {
+			return WriteVocabFile(*out, *format, rows)
+		}
+		return WriteVocab(stdout, *format, rows)
+	case "import":
+		if set.NArg() != 1 {
+			return fmt.Errorf("usage: tester vocab import [flags] file")
+		}
+		file, err := os.Open(set.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		rows, err := ReadVocab(file, *format)
+		if err != nil {
+			return err
+		}
+		merged, err := MergeVocabRows(db, *lang, rows)
+		fmt.Fprintf(stdout, "imported %d of %d words\n", merged, len(rows))
+		return err
+	}
+	return fmt.Errorf("unknown vocab command '%s'", args[0])
+}
+
+// ReadVocabRows reads every language if lang is "".
+func ReadVocabRows(db *bolt.DB, lang string) ([]VocabRow, error) {
+	langs := []string{lang}
+	if lang == "" {
+		var err error
+		if langs, err = DBLanguages(db); err != nil {
+			return nil, err
+		}
+	}
+	var result []VocabRow
+	for _, l := range langs {
+		err := DBForEachWord(db, l, func(word string, rec WordRecord) error {
+			result = append(result, VocabRow{Word: word, Lang: l, Record: rec})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// MergeVocabRows never overrides what we already know about a word, see MergeWordRecord.
+func MergeVocabRows(db *bolt.DB, lang string, rows []VocabRow) (int, error) {
+	by_lang := make(map[string]map[string]WordRecord)
+	for _, row := range rows {
+		l := row.Lang
+		if lang != "" {
+			l = lang
+		}
+		if l == "" {
+			l = DEFAULT_LANGUAGE
+		}
+		if by_lang[l] == nil {
+			by_lang[l] = make(map[string]WordRecord)
+		}
+		by_lang[l][FoldWord(row.Word)] = row.Record
+	}
+
+	langs := make([]string, 0, len(by_lang))
+	for l := range by_lang {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+
+	total := 0
+	for _, l := range langs {
+		merged, err := DBMergeWords(db, l, by_lang[l])
+		total += merged
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func WriteVocab(w io.Writer, format string, rows []VocabRow) error {
+	out := csv.NewWriter(w)
+	switch format {
+	case VOCAB_FORMAT_CSV:
+	case VOCAB_FORMAT_TSV:
+		out.Comma = '\t'
+	case VOCAB_FORMAT_ANKI:
+		out.Comma = '\t'
+		fmt.Fprint(w, "#separator:tab\n#html:false\n#columns:word\tnote\tsentence\ttags\n#tags column:4\n")
+		for _, row := range rows {
+			sentence := ""
+			if row.Record.Context != nil {
+				sentence = row.Record.Context.Sentence
+			}
+			tags := "tester lang::" + row.Lang + " status::" + row.Record.Status.String()
+			if err := out.Write([]string{row.Word, row.Record.Note, sentence, tags}); err != nil {
+				return err
+			}
+		}
+		out.Flush()
+		return out.Error()
+	default:
+		return fmt.Errorf("unknown vocab format '%s'", format)
+	}
+
+	if err := out.Write(VOCAB_COLUMNS); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := out.Write(vocab_row_to_fields(row)); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// WriteVocabFile writes to a temporary file next to path and renames it to path when it's all there,
+// so a failed export doesn't leave half a file behind (or clobber the last good one).
+func WriteVocabFile(path string, format string, rows []VocabRow) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name()) // NOTE: fails once it's renamed, that's fine
+	if err = WriteVocab(file, format, rows); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("Failed to write '%s': '%v'", path, err)
+	}
+	return nil
+}
+
+// skip_anki_header returns data without anki's "#key:value" lines and how many there were.
+// NOTE: only the lines at the top are skipped, a word can start with '#' too
+func skip_anki_header(data string) (string, int) {
+	skipped := 0
+	for strings.HasPrefix(data, "#") {
+		line, rest, _ := strings.Cut(data, "\n")
+		key, _, ok := strings.Cut(strings.TrimPrefix(line, "#"), ":")
+		if !ok || !ANKI_HEADERS[key] {
+			break
+		}
+		data = rest
+		skipped += 1
+	}
+	return data, skipped
+}
+
+func ReadVocab(r io.Reader, format string) ([]VocabRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text, skipped := string(data), 0
+	if format == VOCAB_FORMAT_ANKI {
+		text, skipped = skip_anki_header(text)
+	}
+	in := csv.NewReader(strings.NewReader(text))
+	in.LazyQuotes = true
+	in.FieldsPerRecord = -1
+	switch format {
+	case VOCAB_FORMAT_CSV:
+	case VOCAB_FORMAT_TSV, VOCAB_FORMAT_ANKI:
+		in.Comma = '\t'
+	default:
+		return nil, fmt.Errorf("unknown vocab format '%s'", format)
+	}
+
+	var result []VocabRow
+	for first := true; ; first = false {
+		fields, err := in.Read()
+		if err == io.EOF {
+			break
+		}
+		if perr, ok := err.(*csv.ParseError); ok {
+			return nil, fmt.Errorf("line %d: %v", perr.Line+skipped, perr.Err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := in.FieldPos(0)
+		var row VocabRow
+		if format == VOCAB_FORMAT_ANKI {
+			row, err = anki_fields_to_row(fields)
+		} else {
+			if first && len(fields) > 0 && fields[0] == VOCAB_COLUMNS[0] {
+				continue // header
+			}
+			row, err = fields_to_vocab_row(fields)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line+skipped, err)
+		}
+		if row.Word != "" {
+			result = append(result, row)
+		}
+	}
+	return result, nil
+}
+
+func vocab_row_to_fields(row VocabRow) []string {
+	rec := &row.Record
+	fields := make([]string, len(VOCAB_COLUMNS))
+	fields[0] = row.Word
+	fields[1] = row.Lang
+	fields[2] = rec.Status.String()
+	fields[3] = rec.Note
+	if rec.Context != nil {
+		fields[4] = rec.Context.Sentence
+		fields[5] = rec.Context.File
+		fields[6] = strconv.Itoa(rec.Context.Offset)
+	}
+	fields[7] = format_time(rec.FirstSeen)
+	fields[8] = format_time(rec.LastSeen)
+	fields[9] = strconv.FormatUint(uint64(rec.Lookups), 10)
+	if rec.Review != nil {
+		fields[10] = strconv.FormatFloat(rec.Review.Ease, 'f', -1, 64)
+		fields[11] = strconv.Itoa(rec.Review.Interval)
+		fields[12] = strconv.Itoa(rec.Review.Repetitions)
+		fields[13] = format_time(rec.Review.Due)
+		if len(rec.Review.History) > 0 {
+			history, _ := json.Marshal(rec.Review.History)
+			fields[14] = string(history)
+		}
+	}
+	return fields
+}
+
+func fields_to_vocab_row(fields []string) (VocabRow, error) {
+	var row VocabRow
+	get := func(i int) string {
+		if i < len(fields) {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+	var err error
+	row.Word = get(0)
+	row.Lang = get(1)
+	if row.Record.Status, err = ParseWordStatus(get(2)); err != nil {
+		return row, err
+	}
+	row.Record.Note = get(3)
+	if get(4) != "" {
+		ctx := WordContext{Sentence: get(4), File: get(5)}
+		if get(6) != "" {
+			if ctx.Offset, err = strconv.Atoi(get(6)); err != nil {
+				return row, fmt.Errorf("bad offset '%s'", get(6))
+			}
+		}
+		row.Record.Context = &ctx
+	}
+	if row.Record.FirstSeen, err = parse_time(get(7)); err != nil {
+		return row, err
+	}
+	if row.Record.LastSeen, err = parse_time(get(8)); err != nil {
+		return row, err
+	}
+	if get(9) != "" {
+		lookups, err := strconv.ParseUint(get(9), 10, 32)
+		if err != nil {
+			return row, fmt.Errorf("bad lookups '%s'", get(9))
+		}
+		row.Record.Lookups = uint32(lookups)
+	}
+	if get(10) != "" {
+		review := ReviewData{}
+		if review.Ease, err = strconv.ParseFloat(get(10), 64); err != nil {
+			return row, fmt.Errorf("bad ease '%s'", get(10))
+		}
+		if review.Interval, err = strconv.Atoi(get(11)); err != nil {
+			return row, fmt.Errorf("bad interval '%s'", get(11))
+		}
+		if review.Repetitions, err = strconv.Atoi(get(12)); err != nil {
+			return row, fmt.Errorf("bad reps '%s'", get(12))
+		}
+		if review.Due, err = parse_time(get(13)); err != nil {
+			return row, err
+		}
+		if get(14) != "" {
+			if err = json.Unmarshal([]byte(get(14)), &review.History); err != nil {
+				return row, fmt.Errorf("bad history '%s'", get(14))
+			}
+		}
+		row.Record.Review = &review
+	}
+	return row, nil
+}
+
+func anki_fields_to_row(fields []string) (VocabRow, error) {
+	var row VocabRow
+	if len(fields) < 4 {
+		return row, fmt.Errorf("expected 4 columns, got %d", len(fields))
+	}
+	row.Word = strings.TrimSpace(fields[0])
+	row.Record.Note = fields[1]
+	if fields[2] != "" {
+		row.Record.Context = &WordContext{Sentence: fields[2]}
+	}
+	for _, tag := range strings.Fields(fields[3]) {
+		var err error
+		switch {
+		case strings.HasPrefix(tag, "lang::"):
+			row.Lang = strings.TrimPrefix(tag, "lang::")
+		case strings.HasPrefix(tag, "status::"):
+			if row.Record.Status, err = ParseWordStatus(strings.TrimPrefix(tag, "status::")); err != nil {
+				return row, err
+			}
+		}
+	}
+	return row, nil
+}
+
+func format_time(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func parse_time(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("bad time '%s', expected RFC 3339", s)
+	}
+	return t, nil
+}
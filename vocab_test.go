@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func vocab_test_rows() []VocabRow {
+	seen := time.Date(2022, 11, 5, 10, 0, 0, 0, time.UTC)
+	review := NewReviewData(seen)
+	*review = NextReview(*review, GRADE_GOOD, seen)
+	*review = NextReview(*review, GRADE_HARD, seen.AddDate(0, 0, 1))
+	return []VocabRow{
+		{Word: "maison", Lang: "fr", Record: WordRecord{
+			Status: WORD_STATUS_LEARNING, FirstSeen: seen, LastSeen: seen.Add(time.Hour), Lookups: 2,
+			Note: "house, \"home\"", Review: review,
+			Context: &WordContext{File: "book.txt", Offset: 42, Sentence: "La maison est grande.\tOui."},
+		}},
+		{Word: "chat", Lang: "fr", Record: WordRecord{Status: WORD_STATUS_KNOWN, FirstSeen: seen, LastSeen: seen}},
+	}
+}
+
+func TestVocabRoundTrip(t *testing.T) {
+	for _, format := range []string{VOCAB_FORMAT_CSV, VOCAB_FORMAT_TSV} {
+		var buf bytes.Buffer
+		rows := vocab_test_rows()
+		if err := WriteVocab(&buf, format, rows); err != nil {
+			t.Fatalf("WriteVocab(%s) failed: %v", format, err)
+		}
+		got, err := ReadVocab(&buf, format)
+		if err != nil {
+			t.Fatalf("ReadVocab(%s) failed: %v", format, err)
+		}
+		if len(got) != len(rows) {
+			t.Fatalf("ReadVocab(%s) failed: got (%d) rows expected (%d)", format, len(got), len(rows))
+		}
+		for i := range rows {
+			in, out := rows[i], got[i]
+			if out.Word != in.Word || out.Lang != in.Lang || out.Record.Status != in.Record.Status ||
+				out.Record.Note != in.Record.Note || !out.Record.FirstSeen.Equal(in.Record.FirstSeen) ||
+				out.Record.Lookups != in.Record.Lookups {
+				t.Errorf("TestVocabRoundTrip(%s) failed: got (%+v) expected (%+v)", format, out, in)
+			}
+			if (in.Record.Context == nil) != (out.Record.Context == nil) ||
+				in.Record.Context != nil && *in.Record.Context != *out.Record.Context {
+				t.Errorf("TestVocabRoundTrip(%s) failed: got context (%+v) expected (%+v)", format, out.Record.Context, in.Record.Context)
+			}
+			if (in.Record.Review == nil) != (out.Record.Review == nil) ||
+				in.Record.Review != nil && (!out.Record.Review.Due.Equal(in.Record.Review.Due) ||
+					out.Record.Review.Ease != in.Record.Review.Ease || len(out.Record.Review.History) != len(in.Record.Review.History)) {
+				t.Errorf("TestVocabRoundTrip(%s) failed: got review (%+v) expected (%+v)", format, out.Record.Review, in.Record.Review)
+			}
+			if in.Record.Review != nil && len(in.Record.Review.History) > 0 {
+				a, b := in.Record.Review.History[1], out.Record.Review.History[1]
+				if !a.Time.Equal(b.Time) || a.Grade != b.Grade || a.Interval != b.Interval {
+					t.Errorf("TestVocabRoundTrip(%s) failed: got history (%+v) expected (%+v)", format, b, a)
+				}
+			}
+		}
+	}
+}
+
+func TestVocabAnki(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteVocab(&buf, VOCAB_FORMAT_ANKI, vocab_test_rows()); err != nil {
+		t.Fatalf("WriteVocab failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "#separator:tab\n") {
+		t.Errorf("TestVocabAnki failed: missing anki header in (%q)", buf.String())
+	}
+	got, err := ReadVocab(&buf, VOCAB_FORMAT_ANKI)
+	if err != nil {
+		t.Fatalf("ReadVocab failed: %v", err)
+	}
+	if len(got) != 2 || got[0].Word != "maison" || got[0].Lang != "fr" || got[0].Record.Status != WORD_STATUS_LEARNING ||
+		got[0].Record.Context == nil || got[0].Record.Context.Sentence != "La maison est grande.\tOui." {
+		t.Errorf("TestVocabAnki failed: got (%+v)", got)
+	}
+}
+
+func TestReadVocabHash(t *testing.T) {
+	// a word can start with '#', only anki's header lines at the top are skipped
+	tsv := "#hashtag\tfr\tknown\n"
+	got, err := ReadVocab(strings.NewReader(tsv), VOCAB_FORMAT_TSV)
+	if err != nil || len(got) != 1 || got[0].Word != "#hashtag" {
+		t.Errorf("ReadVocab(tsv) failed: got (%+v, %v)", got, err)
+	}
+	anki := "#separator:tab\n#tags column:4\n#hashtag\t\t\ttester\n#nope:x\t\t\ttester\n"
+	got, err = ReadVocab(strings.NewReader(anki), VOCAB_FORMAT_ANKI)
+	if err != nil || len(got) != 2 || got[0].Word != "#hashtag" || got[1].Word != "#nope:x" {
+		t.Errorf("ReadVocab(anki) failed: got (%+v, %v)", got, err)
+	}
+
+	// the line numbers count the header and the lines inside quotes
+	csv := strings.Join(VOCAB_COLUMNS, ",") + "\nmaison,fr,known,\"two\nlines\"\nchat,fr,unknown-status\n"
+	if _, err := ReadVocab(strings.NewReader(csv), VOCAB_FORMAT_CSV); err == nil || !strings.HasPrefix(err.Error(), "line 4:") {
+		t.Errorf("ReadVocab(csv) failed: got (%v) expected an error on line 4", err)
+	}
+	anki = "#separator:tab\n#html:false\nchat\tshort\n"
+	if _, err := ReadVocab(strings.NewReader(anki), VOCAB_FORMAT_ANKI); err == nil || !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Errorf("ReadVocab(anki) failed: got (%v) expected an error on line 3", err)
+	}
+}
+
+func TestWriteVocabFile(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/vocab.csv"
+	if err := os.WriteFile(path, []byte("the last export"), FILE_MODE_RW); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteVocabFile(path, "xml", vocab_test_rows()); err == nil {
+		t.Errorf("WriteVocabFile should fail on an unknown format")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "the last export" {
+		t.Errorf("WriteVocabFile failed: a failed export left (%q)", data)
+	}
+	if err := WriteVocabFile(path, VOCAB_FORMAT_CSV, vocab_test_rows()); err != nil {
+		t.Fatalf("WriteVocabFile failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); !strings.HasPrefix(string(data), strings.Join(VOCAB_COLUMNS, ",")) {
+		t.Errorf("WriteVocabFile failed: got (%q)", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("WriteVocabFile failed: left (%d) files behind", len(entries))
+	}
+}
+
+func TestMergeVocabRows(t *testing.T) {
+	db := openTestDB(t)
+	if err := DBInsert(db, "fr", "chat"); err != nil {
+		t.Fatalf("DBInsert failed: %v", err)
+	}
+	if err := DBUpdateWord(db, "fr", "chat", func(rec *WordRecord) error {
+		rec.Note = "cat"
+		rec.Status = WORD_STATUS_IGNORED
+		return nil
+	}); err != nil {
+		t.Fatalf("DBUpdateWord failed: %v", err)
+	}
+
+	rows := vocab_test_rows()
+	rows[1].Record.Note = "a cat"
+	merged, err := MergeVocabRows(db, "", rows)
+	if err != nil {
+		t.Fatalf("MergeVocabRows failed: %v", err)
+	}
+	if merged != 2 {
+		t.Errorf("MergeVocabRows failed: got (%d) merged expected (2)", merged)
+	}
+
+	chat, err := DBGetWord(db, "fr", "chat")
+	if err != nil {
+		t.Fatalf("DBGetWord failed: %v", err)
+	}
+	if chat.Note != "cat" || chat.Status != WORD_STATUS_IGNORED {
+		t.Errorf("MergeVocabRows failed: overrode (%+v)", chat)
+	}
+	if !chat.FirstSeen.Equal(rows[1].Record.FirstSeen) {
+		t.Errorf("MergeVocabRows failed: got first seen (%v) expected (%v)", chat.FirstSeen, rows[1].Record.FirstSeen)
+	}
+
+	// importing the same file twice changes nothing
+	if merged, err = MergeVocabRows(db, "", rows); err != nil || merged != 0 {
+		t.Errorf("MergeVocabRows failed: got (%d, %v) expected (0, nil)", merged, err)
+	}
+}